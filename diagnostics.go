@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+)
+
+// Write the diagnostics in one of the supported formats
+func writeDiagnostics(w io.Writer, format string, diags checker.Diagnostics) error {
+	switch format {
+	case "json":
+		return writeJSON(w, diags)
+	case "sarif":
+		return writeSARIF(w, diags)
+	}
+
+	for _, diag := range diags {
+		fmt.Fprintln(w, diag)
+	}
+	return nil
+}
+
+// JSON lines output, one diagnostic per line
+type jsonRange struct {
+	Start ast.Pos `json:"start"`
+	End   ast.Pos `json:"end"`
+}
+
+type jsonDiagnostic struct {
+	File     string    `json:"file"`
+	Range    jsonRange `json:"range"`
+	Severity string    `json:"severity"`
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+}
+
+func writeJSON(w io.Writer, diags checker.Diagnostics) error {
+	enc := json.NewEncoder(w)
+	for _, d := range diags {
+		err := enc.Encode(jsonDiagnostic{d.File, jsonRange{d.Start, d.End},
+			d.Severity, d.Code, d.Message})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subset of the SARIF 2.1.0 log format
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func writeSARIF(w io.Writer, diags checker.Diagnostics) error {
+	rules := make([]sarifRule, len(checker.Rules))
+	index := map[string]int{}
+	for i, id := range checker.Rules {
+		rules[i] = sarifRule{ID: id}
+		index[id] = i
+	}
+
+	results := []sarifResult{}
+	for _, d := range diags {
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: d.File}}
+		// Positions are unknown for some errors, e.g. internal ones
+		if d.Start.Line > 0 {
+			location.Region = &sarifRegion{d.Start.Line, d.Start.Column,
+				d.End.Line, d.End.Column}
+		}
+
+		results = append(results, sarifResult{RuleID: d.Code,
+			RuleIndex: index[d.Code], Level: d.Severity,
+			Message:   sarifMessage{d.Message},
+			Locations: []sarifLocation{{location}}})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{sarifDriver{Name: "meego",
+				InformationURI: "https://github.com/aniketp/meego", Rules: rules}},
+			Results: results}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
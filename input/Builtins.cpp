@@ -1,5 +1,6 @@
-// C++ Builtins
+// C++ Builtins, generated from checker.Builtins. DO NOT EDIT.
 
+#include <cstdlib>
 #include <iostream>
 #include <string>
 
@@ -8,7 +9,6 @@ using namespace std;
 
 // Nothing Class
 class Nothing {
-	
 };
 
 // Base Class
@@ -17,50 +17,69 @@ public:
 	string val;
 	Nothing PRINT(void) {
 		cout << val << endl;
+		return Nothing();
 	}
 };
 
 const string True = "true";
 const string False = "false";
 
+// Integer division, which fails on a zero divisor rather than crash
+int divide(int x, int y) {
+	if (y == 0) {
+		cerr << "runtime error: division by zero" << endl;
+		exit(1);
+	}
+	return x / y;
+}
+
 // Bool Class
 class Bool: public Base {
 public:
 	Bool(string x) {
 		val = x;
 	}
+	Bool() : Bool((false) ? True : False) {}
+
+	Bool AND(Bool arg0) {
+		return Bool((((*this).val == True) && (arg0.val == True)) ? True : False);
+	}
+
+	Bool OR(Bool arg0) {
+		return Bool((((*this).val == True) || (arg0.val == True)) ? True : False);
+	}
 
-	Bool And(Bool x) {
-		if (val == False || x.val == False) {
-			return Bool(False);
-		}
-		return Bool(False);
+	Bool EQ(Bool arg0) {
+		return Bool((((*this).val == True) == (arg0.val == True)) ? True : False);
 	}
 
-	Bool Or(Bool x) {
-		if (val == True || x.val == True) {
-			return Bool(True);
-		}
-		return Bool(False);
+	Bool NEQ(Bool arg0) {
+		return Bool((((*this).val == True) != (arg0.val == True)) ? True : False);
+	}
+
+	Bool NOT() {
+		return Bool((!((*this).val == True)) ? True : False);
 	}
 };
 
 // String Class
 class String: public Base {
-public: 
+public:
 	String(string x) {
 		val = x;
 	}
-	String PLUS(String str) {
-		return String(val + str.val);
+	String() : String("") {}
+
+	String PLUS(String arg0) {
+		return String((*this).val + arg0.val);
+	}
+
+	Bool EQ(String arg0) {
+		return Bool(((*this).val == arg0.val) ? True : False);
 	}
 
-	Bool EQ(String str) {
-		if (val == str.val) {
-			return Bool(True);
-		} else {
-			return Bool(False);
-		}
+	Bool NEQ(String arg0) {
+		return Bool(((*this).val != arg0.val) ? True : False);
 	}
 };
 
@@ -72,35 +91,49 @@ public:
 		val = to_string(x);
 		valInt = x;
 	}
+	Int() : Int(0) {}
+
+	Int PLUS(Int arg0) {
+		return Int((*this).valInt + arg0.valInt);
+	}
+
+	Int MINUS(Int arg0) {
+		return Int((*this).valInt - arg0.valInt);
+	}
+
+	Int TIMES(Int arg0) {
+		return Int((*this).valInt * arg0.valInt);
+	}
+
+	Int DIVIDE(Int arg0) {
+		return Int(divide((*this).valInt, arg0.valInt));
+	}
+
+	Bool LT(Int arg0) {
+		return Bool(((*this).valInt < arg0.valInt) ? True : False);
+	}
 
-	Int PLUS(Int num) {
-		return Int(valInt + num.valInt);
+	Bool GT(Int arg0) {
+		return Bool(((*this).valInt > arg0.valInt) ? True : False);
 	}
 
-	Int MINUS(Int num) {
-		return Int(valInt - num.valInt);
+	Bool LTE(Int arg0) {
+		return Bool(((*this).valInt <= arg0.valInt) ? True : False);
 	}
 
-	Int TIMES(Int num) {
-		return Int(valInt * num.valInt);
+	Bool GTE(Int arg0) {
+		return Bool(((*this).valInt >= arg0.valInt) ? True : False);
 	}
 
-	Bool GT(Int num) {
-		if (valInt < num.valInt) {
-			return Bool(False);
-		} else {
-			return Bool(True);
-		}
+	Bool EQ(Int arg0) {
+		return Bool(((*this).valInt == arg0.valInt) ? True : False);
 	}
 
-	Bool EQ(Int num) {
-		if (valInt == num.valInt) {
-			return Bool(True);
-		}
-		return Bool(False);
+	Bool NEQ(Int arg0) {
+		return Bool(((*this).valInt != arg0.valInt) ? True : False);
 	}
 
-	String Stringify() {
-		return String(val);
+	Int NEG() {
+		return Int(-(*this).valInt);
 	}
 };
@@ -2,84 +2,398 @@ package main
 
 import (
 	"bytes"
+	_ "embed"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
-	"github.com/aniketp/meego/src/ast"
 	"github.com/aniketp/meego/src/checker"
-	"github.com/aniketp/meego/src/codegen"
-	"github.com/aniketp/meego/src/lexer"
-	"github.com/aniketp/meego/src/parser"
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+	"github.com/aniketp/meego/src/repl"
+	"github.com/aniketp/meego/src/toolchain"
+	"github.com/aniketp/meego/src/vm"
 )
 
+// Exit codes of the driver
+const (
+	exitOK            = 0 // no errors
+	exitCompileErrors = 1 // errors in the compiled program
+	exitUsage         = 2 // bad invocation (same as the flag package)
+	exitInternalError = 3 // bug in the compiler itself
+)
+
+// C++ runtime of the builtin types, included by the generated code
+//
+//go:embed input/Builtins.cpp
+var builtins []byte
+
+const usage = `usage: meego <command> [flags] file.meego
+
+Commands:
+  check   parse and type-check the program
+  emit    print the generated C++ or, with -bytecode, serialized bytecode
+          (or write it to -o)
+  build   compile the program to a binary named -o
+  run     compile the program and run it (the default), or interpret
+          it with -interp or run it on the bytecode VM with -vm
+  disasm  print the bytecode of the program (or of serialized bytecode)
+  repl    evaluate statements and expressions interactively (no file)
+
+Run 'meego <command> -h' for the flags of a command.
+`
+
+// Flags shared by the subcommands
+type options struct {
+	format      string // format of the diagnostics
+	diagnostics string // file to write the diagnostics to
+	output      string // path of the generated C++ or binary
+	cxxflags    string // extra flags for the C++ compiler
+	interp      bool   // run through the interpreter instead of C++
+	vm          bool   // run through the bytecode VM instead of C++
+	bytecode    bool   // emit serialized bytecode instead of C++
+	toolchain   *toolchain.Toolchain
+}
+
+type command func(file string, opts *options) int
+
+var commands = map[string]command{
+	"check":  checkCommand,
+	"emit":   emitCommand,
+	"build":  buildCommand,
+	"run":    runCommand,
+	"disasm": disasmCommand,
+	"repl":   replCommand,
+}
+
 func check(err error) {
 	if err != nil {
 		panic(err)
 	}
 }
 
-func Parse(input string) *ast.Program {
-	l := lexer.NewLexer([]byte(input))
-	p := parser.NewParser()
+// Compile the generated code into a binary at output. Warnings of the C++
+// compiler are passed on to stderr.
+func Compile(code, output string, opts *options) error {
+	tc := *opts.toolchain
+	tc.Flags = strings.Fields(opts.cxxflags)
+	warnings, err := compiler.Build([]byte(code), builtins, output, &tc)
+	if err != nil {
+		return err
+	}
+
+	os.Stderr.WriteString(warnings)
+	return nil
+}
+
+// Report a C++ compiler that couldn't run, e.g. a missing -cxx, as a problem
+// of the setup. Generated code that it rejects is a bug in meego.
+func toolchainError(err error) int {
+	if e, ok := err.(*toolchain.Error); !ok || e.Status >= 0 {
+		panic(err)
+	}
 
-	node, err := p.Parse(l)
-	check(err)
-	program, _ := node.(*ast.Program)
-	return program
+	fmt.Fprintln(os.Stderr, err)
+	return exitUsage
 }
 
-func TypeCheck(program *ast.Program) {
-	err := checker.Checker(program)
-	check(err)
+// Compile the file to C++, writing out any diagnostics. The result is only
+// returned if the program has no errors, otherwise so is the exit code.
+func frontend(file string, opts *options, checkOnly bool) (*compiler.Result, int) {
+	input, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitUsage
+	}
+
+	res, err := compiler.Compile(input, &compiler.Options{File: file, CheckOnly: checkOnly})
+	diags := res.Diagnostics
+	if len(diags) != 0 || opts.format == "sarif" {
+		if err := report(opts, diags); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, exitUsage
+		}
+	}
+
+	for _, diag := range diags {
+		if diag.Code == checker.INTERNAL_ERROR {
+			return nil, exitInternalError
+		}
+	}
+
+	if err != nil {
+		return nil, exitCompileErrors
+	}
+	return res, exitOK
 }
 
-func Compile(code bytes.Buffer) string {
-	f, err := os.Create("./input/main.cpp")
+// Compile the file to bytecode, unless it's serialized bytecode already
+func loadBytecode(file string, opts *options) (*vm.Program, int) {
+	input, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitUsage
+	}
+
+	if vm.IsBytecode(input) {
+		program, err := vm.Decode(bytes.NewReader(input))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+			return nil, exitUsage
+		}
+		return program, exitOK
+	}
+
+	res, code := frontend(file, opts, true)
+	if res == nil {
+		return nil, code
+	}
+
+	program, err := vm.Compile(res.Program)
 	check(err)
+	return program, exitOK
+}
+
+// Write the diagnostics where and how the options ask for
+func report(opts *options, diags checker.Diagnostics) error {
+	w := os.Stderr
+	if opts.diagnostics != "" {
+		f, err := os.Create(opts.diagnostics)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return writeDiagnostics(w, opts.format, diags)
+}
+
+func checkCommand(file string, opts *options) int {
+	_, code := frontend(file, opts, true)
+	return code
+}
+
+func emitCommand(file string, opts *options) int {
+	if opts.bytecode {
+		return emitBytecode(file, opts)
+	}
+
+	res, code := frontend(file, opts, false)
+	if res == nil {
+		return code
+	}
+
+	if opts.output == "" {
+		os.Stdout.WriteString(res.Code)
+		return exitOK
+	}
+
+	err := ioutil.WriteFile(opts.output, []byte(res.Code), 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+func emitBytecode(file string, opts *options) int {
+	program, code := loadBytecode(file, opts)
+	if program == nil {
+		return code
+	}
+
+	if opts.output == "" {
+		check(program.Encode(os.Stdout))
+		return exitOK
+	}
+
+	f, err := os.Create(opts.output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
 	defer f.Close()
 
-	f.Write(code.Bytes())
+	check(program.Encode(f))
+	return exitOK
+}
+
+func disasmCommand(file string, opts *options) int {
+	program, code := loadBytecode(file, opts)
+	if program == nil {
+		return code
+	}
+
+	program.Disassemble(os.Stdout)
+	return exitOK
+}
+
+func buildCommand(file string, opts *options) int {
+	res, code := frontend(file, opts, false)
+	if res == nil {
+		return code
+	}
+
+	// Name the binary after the source file by default
+	output := opts.output
+	if output == "" {
+		output = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	if err := Compile(res.Code, output, opts); err != nil {
+		return toolchainError(err)
+	}
+	return exitOK
+}
 
-	var out bytes.Buffer
-	cmd1 := exec.Command("g++", "-std=c++11", "-o", "apple", "./input/main.cpp",
-		"./input/Builtins.cpp")
-	cmd1.Stderr = &out
-	err = cmd1.Run()
+func runCommand(file string, opts *options) int {
+	if opts.vm {
+		program, code := loadBytecode(file, opts)
+		if program == nil {
+			return code
+		}
+
+		return runtimeError(vm.Run(program, os.Stdout))
+	}
+
+	res, code := frontend(file, opts, opts.interp)
+	if res == nil {
+		return code
+	}
+
+	if opts.interp {
+		return runtimeError(interp.Run(res.Program, res.Info, os.Stdout))
+	}
 
-	// Check if output was a valid one
-	if len(out.String()) != 0 {
-		panic(fmt.Sprintf("error: %s", out.String()))
+	// Unless asked to keep it, the binary is thrown away after running
+	binary := opts.output
+	if binary == "" {
+		dir, err := ioutil.TempDir("", "meego")
+		check(err)
+		defer os.RemoveAll(dir)
+		binary = filepath.Join(dir, "main")
 	}
 
-	// Now, execute the resulting 'apple' binary
-	cmd := exec.Command("./apple")
-	var outb bytes.Buffer
-	cmd.Stdout = &outb
+	binary, err := filepath.Abs(binary)
+	check(err)
+	if err := Compile(res.Code, binary, opts); err != nil {
+		return toolchainError(err)
+	}
+
+	// Now, execute the resulting binary
+	cmd := exec.Command(binary)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 	err = cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return exit.ExitCode()
+	}
 	check(err)
+	return exitOK
+}
+
+// Report an error of the running program as the C++ runtime does, e.g. a
+// division by zero. Any other error is internal.
+func runtimeError(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if _, ok := err.(interp.RuntimeError); !ok {
+		panic(err)
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+	return exitCompileErrors
+}
+
+func replCommand(file string, opts *options) int {
+	repl.Start(os.Stdin, os.Stdout)
+	return exitOK
+}
 
-	// This is the generated output of our transpiled program
-	return outb.String()
+// Run a command on the file, turning a panic into an internal error
+func execute(cmd command, file string, opts *options) (code int) {
+	defer func() {
+		// Anything that panics is a bug in meego rather than in the program
+		if r := recover(); r != nil {
+			report(opts, checker.Diagnostics{compiler.InternalDiagnostic(file, r)})
+			code = exitInternalError
+		}
+	}()
 
+	return cmd(file, opts)
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		panic("Provide a valid file name")
+	args := os.Args[1:]
+	name := "run"
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
 	}
 
-	path := os.Args[1]
-	absPath, _ := filepath.Abs(path)
-	input, err := ioutil.ReadFile(absPath)
-	check(err)
+	opts := &options{}
+	flags := flag.NewFlagSet("meego "+name, flag.ExitOnError)
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the diagnostics: text, json (one object per line) or sarif")
+	flags.StringVar(&opts.diagnostics, "diagnostics", "",
+		"write the diagnostics to this file instead of stderr")
+	// The toolchain defaults to the CXX and CXXFLAGS environment variables
+	opts.toolchain = toolchain.Default()
+	if name != "check" && name != "disasm" && name != "repl" {
+		tc := opts.toolchain
+		flags.StringVar(&tc.CXX, "cxx", tc.CXX, "C++ compiler (g++ or clang++)")
+		flags.StringVar(&opts.cxxflags, "cxxflags", strings.Join(tc.Flags, " "),
+			"extra flags for the C++ compiler, separated by spaces")
+		flags.StringVar(&tc.Std, "std", tc.Std, "C++ standard")
+		flags.StringVar(&tc.Opt, "O", tc.Opt, "optimization level, e.g. 0, 2 or s")
+	}
+
+	switch name {
+	case "emit":
+		flags.StringVar(&opts.output, "o", "", "write the C++ to this file instead of stdout")
+		flags.BoolVar(&opts.bytecode, "bytecode", false, "emit serialized bytecode instead of C++")
+	case "build":
+		flags.StringVar(&opts.output, "o", "",
+			"path of the binary (default: the file name without extension)")
+	case "run":
+		flags.StringVar(&opts.output, "o", "", "keep the binary at this path")
+		flags.BoolVar(&opts.interp, "interp", false,
+			"evaluate the program directly, without a C++ compiler")
+		flags.BoolVar(&opts.vm, "vm", false,
+			"run the program (or serialized bytecode) on the bytecode VM")
+	}
+
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fmt.Fprintf(os.Stderr, "\nFlags of 'meego %s':\n", name)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Every command but the REPL works on a single file
+	files := 1
+	if name == "repl" {
+		files = 0
+	}
+
+	if flags.NArg() != files {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+
+	switch opts.format {
+	case "text", "json", "sarif":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown diagnostics format %q\n", opts.format)
+		os.Exit(exitUsage)
+	}
 
-	program := Parse(string(input))
-	TypeCheck(program)
-	// Generate vanilla C++
-	code := codegen.GenWrapper(program)
-	result := Compile(code)
-	fmt.Println(result)
+	// Diagnostics refer to the file by the path it was given as
+	os.Exit(execute(commands[name], flags.Arg(0), opts))
 }
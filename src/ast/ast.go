@@ -32,6 +32,15 @@ func (is IfStatement) TokenLiteral() string {
 	return "IfStatement"
 }
 
+func (ss SwitchStatement) statementNode() {}
+func (ss SwitchStatement) TokenLiteral() string {
+	return "SwitchStatement"
+}
+
+func (cc CaseClause) TokenLiteral() string {
+	return "CaseClause"
+}
+
 func (bs BlockStatement) statementNode() {}
 func (bs BlockStatement) TokenLiteral() string {
 	return "BlockStatement"
@@ -42,6 +51,21 @@ func (is InitStatement) TokenLiteral() string {
 	return "InitStatement"
 }
 
+func (fs ForStatement) statementNode() {}
+func (fs ForStatement) TokenLiteral() string {
+	return "ForStatement"
+}
+
+func (bs BreakStatement) statementNode() {}
+func (bs BreakStatement) TokenLiteral() string {
+	return "BreakStatement"
+}
+
+func (cs ContinueStatement) statementNode() {}
+func (cs ContinueStatement) TokenLiteral() string {
+	return "ContinueStatement"
+}
+
 func (fs FunctionStatement) statementNode() {}
 func (fs FunctionStatement) TokenLiteral() string {
 	return "FunctionStatement"
@@ -73,14 +97,39 @@ func (oe InfixExpression) TokenLiteral() string {
 	return string(oe.Token.Lit)
 }
 
+func (pe PrefixExpression) expressionNode() {}
+func (pe PrefixExpression) TokenLiteral() string {
+	return string(pe.Token.Lit)
+}
+
 func (fc FunctionCall) expressionNode() {}
 func (fc FunctionCall) TokenLiteral() string {
 	return string(fc.Token.Lit)
 }
 
+// Positions
+func (s Span) Pos() Pos {
+	return s.From
+}
+
+func (s Span) End() Pos {
+	return s.To
+}
+
+// Range covered by a single token
+func tokenSpan(t *token.Token) Span {
+	from := Pos{Line: t.Pos.Line, Column: t.Pos.Column}
+	return Span{from, Pos{Line: from.Line, Column: from.Column + len(t.Lit)}}
+}
+
 ////-------------------------------------------
 
 func Error(fun, expected, v string, got interface{}) error {
+	// Syntax errors recovered from by the parser take the place of a node
+	if err, ok := got.(error); ok {
+		return err
+	}
+
 	return fmt.Errorf("AST construction error: In function: %s, expected %s for %s. got=%T", fun, expected, v, got)
 }
 
@@ -96,8 +145,15 @@ func NewProgram(funcs, stmts Attrib) (*Program, error) {
 		return nil, Error("NewProgram", "[]Statement", "funcs", funcs)
 	}
 
+	// The program spans from its first to its last declaration
+	var span Span
+	all := append(append([]Statement{}, f...), s...)
+	if len(all) != 0 {
+		span = Span{all[0].Pos(), all[len(all)-1].End()}
+	}
+
 	// Combine the functions and statements
-	return &Program{Functions: f, Statements: s}, nil
+	return &Program{Functions: f, Statements: s, Span: span}, nil
 }
 
 func NewStatementList() ([]Statement, error) {
@@ -124,7 +180,9 @@ func NewAssignStatement(left, right Attrib) (Statement, error) {
 	}
 
 	// Return the modified assign statement
-	return &AssignStatement{Left: Identifier{Value: string(l.Lit)}, Right: r}, nil
+	ident := Identifier{Value: string(l.Lit), Token: l, Span: tokenSpan(l)}
+	return &AssignStatement{Left: ident, Right: r, Token: l,
+		Span: Span{ident.Pos(), r.End()}}, nil
 }
 
 func NewExpressionStatement(expr Attrib) (Statement, error) {
@@ -133,20 +191,36 @@ func NewExpressionStatement(expr Attrib) (Statement, error) {
 		return nil, Error("NewExpressionStatement", "Expression", "expr", expr)
 	}
 
-	return &ExpressionStatement{Expression: e}, nil
+	return &ExpressionStatement{Expression: e, Span: Span{e.Pos(), e.End()}}, nil
 
 }
 
-func NewBlockStatement(stmts Attrib) (*BlockStatement, error) {
+func NewBlockStatement(lbrace, stmts, rbrace Attrib) (*BlockStatement, error) {
+	l, ok := lbrace.(*token.Token)
+	if !ok {
+		return nil, Error("NewBlockStatement", "*token.Token", "lbrace", lbrace)
+	}
+
 	s, ok := stmts.([]Statement)
 	if !ok {
 		return nil, Error("NewBlockStatement", "[]Statement", "stmts", stmts)
 	}
 
-	return &BlockStatement{Statements: s}, nil
+	r, ok := rbrace.(*token.Token)
+	if !ok {
+		return nil, Error("NewBlockStatement", "*token.Token", "rbrace", rbrace)
+	}
+
+	return &BlockStatement{Statements: s, Token: l,
+		Span: Span{tokenSpan(l).From, tokenSpan(r).To}}, nil
 }
 
-func NewFunctionStatement(name, args, ret, block Attrib) (Statement, error) {
+func NewFunctionStatement(fn, name, args, ret, block Attrib) (Statement, error) {
+	f, ok := fn.(*token.Token)
+	if !ok {
+		return nil, Error("NewFunctionStatement", "*token.Token", "fn", fn)
+	}
+
 	n, ok := name.(*token.Token)
 	if !ok {
 		return nil, Error("NewFunctionStatement", "*token.Token", "name", name)
@@ -170,13 +244,19 @@ func NewFunctionStatement(name, args, ret, block Attrib) (Statement, error) {
 		panic("No return value")
 	}
 
-	return &FunctionStatement{Name: string(n.Lit), Body: b, Parameters: a, Return: string(r.Lit)}, nil
+	return &FunctionStatement{Name: string(n.Lit), Body: b, Parameters: a,
+		Return: string(r.Lit), Token: n, Span: Span{tokenSpan(f).From, b.End()}}, nil
 }
 
-func NewIfStatement(cond, cons, alt Attrib) (Statement, error) {
+func NewIfStatement(tok, cond, cons, alt Attrib) (Statement, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewIfStatement", "*token.Token", "tok", tok)
+	}
+
 	c, ok := cond.(Expression)
 	if !ok {
-		return nil, fmt.Errorf("invalid type of cond. got=%T", cond)
+		return nil, Error("NewIfStatement", "Expression", "cond", cond)
 	}
 
 	cs, ok := cons.(*BlockStatement)
@@ -184,19 +264,153 @@ func NewIfStatement(cond, cons, alt Attrib) (Statement, error) {
 		return nil, fmt.Errorf("invalid type of cons. got=%T", cons)
 	}
 
-	a, ok := alt.(*BlockStatement)
-	if !ok {
+	node := &IfStatement{Condition: c, Block: cs, Token: t,
+		Span: Span{tokenSpan(t).From, cs.End()}}
+
+	// An else block, an else if, or no else at all
+	switch a := alt.(type) {
+	case *BlockStatement:
+		node.Alternative, node.Span.To = a, a.End()
+	case *IfStatement:
+		node.Alternative, node.Span.To = a, a.End()
+	case nil:
+	default:
 		return nil, fmt.Errorf("invalid type of alt. got=%T", alt)
 	}
 
-	return &IfStatement{Condition: c, Block: cs, Alternative: a}, nil
+	return node, nil
+}
+
+func NewSwitchStatement(tok, value, cases, rbrace Attrib) (Statement, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewSwitchStatement", "*token.Token", "tok", tok)
+	}
+
+	v, ok := value.(Expression)
+	if !ok {
+		return nil, Error("NewSwitchStatement", "Expression", "value", value)
+	}
+
+	cs, ok := cases.([]*CaseClause)
+	if !ok {
+		return nil, Error("NewSwitchStatement", "[]*CaseClause", "cases", cases)
+	}
+
+	r, ok := rbrace.(*token.Token)
+	if !ok {
+		return nil, Error("NewSwitchStatement", "*token.Token", "rbrace", rbrace)
+	}
+
+	return &SwitchStatement{Value: v, Cases: cs, Token: t,
+		Span: Span{tokenSpan(t).From, tokenSpan(r).To}}, nil
+}
+
+func NewCaseList() ([]*CaseClause, error) {
+	return []*CaseClause{}, nil
+}
+
+func AppendCase(cases, clause Attrib) ([]*CaseClause, error) {
+	c, ok := clause.(*CaseClause)
+	if !ok {
+		return nil, Error("AppendCase", "*CaseClause", "clause", clause)
+	}
+	return append(cases.([]*CaseClause), c), nil
+}
+
+// A case with its values, or the default case if values is nil. The
+// statements up to the next case make up its body.
+func NewCaseClause(tok, values, colon, stmts Attrib) (*CaseClause, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewCaseClause", "*token.Token", "tok", tok)
+	}
+
+	var vs []Expression
+	if values != nil {
+		vs, ok = values.([]Expression)
+		if !ok {
+			return nil, Error("NewCaseClause", "[]Expression", "values", values)
+		}
+	}
+
+	c, ok := colon.(*token.Token)
+	if !ok {
+		return nil, Error("NewCaseClause", "*token.Token", "colon", colon)
+	}
+
+	s, ok := stmts.([]Statement)
+	if !ok {
+		return nil, Error("NewCaseClause", "[]Statement", "stmts", stmts)
+	}
+
+	body := &BlockStatement{Statements: s, Token: c, Span: tokenSpan(c)}
+	if len(s) != 0 {
+		body.Span.To = s[len(s)-1].End()
+	}
+
+	return &CaseClause{Values: vs, Body: body, Token: t,
+		Span: Span{tokenSpan(t).From, body.End()}}, nil
+}
+
+func NewForStatement(tok, init, cond, post, block Attrib) (Statement, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewForStatement", "*token.Token", "tok", tok)
+	}
+
+	c, ok := cond.(Expression)
+	if !ok {
+		return nil, Error("NewForStatement", "Expression", "cond", cond)
+	}
+
+	b, ok := block.(*BlockStatement)
+	if !ok {
+		return nil, Error("NewForStatement", "BlockStatement", "block", block)
+	}
+
+	// Init and post clauses are only present in the three-clause form
+	var i, p Statement
+	if init != nil {
+		i, ok = init.(Statement)
+		if !ok {
+			return nil, Error("NewForStatement", "Statement", "init", init)
+		}
+	}
+
+	if post != nil {
+		p, ok = post.(Statement)
+		if !ok {
+			return nil, Error("NewForStatement", "Statement", "post", post)
+		}
+	}
+
+	return &ForStatement{Init: i, Condition: c, Post: p, BlockStatement: b,
+		Token: t, Span: Span{tokenSpan(t).From, b.End()}}, nil
+}
+
+func NewBreakStatement(tok Attrib) (Statement, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewBreakStatement", "*token.Token", "tok", tok)
+	}
+
+	return &BreakStatement{Token: t, Span: tokenSpan(t)}, nil
+}
+
+func NewContinueStatement(tok Attrib) (Statement, error) {
+	t, ok := tok.(*token.Token)
+	if !ok {
+		return nil, Error("NewContinueStatement", "*token.Token", "tok", tok)
+	}
+
+	return &ContinueStatement{Token: t, Span: tokenSpan(t)}, nil
 }
 
 // Expressions
 func NewInfixExpression(left, right, oper Attrib) (Expression, error) {
 	l, ok := left.(Expression)
 	if !ok {
-		fmt.Println(left)
 		return nil, Error("NewInfixExpression", "Expression", "left", left)
 	}
 
@@ -210,7 +424,23 @@ func NewInfixExpression(left, right, oper Attrib) (Expression, error) {
 		return nil, Error("NewInfixExpression", "Expression", "right", right)
 	}
 
-	return &InfixExpression{Left: l, Operator: string(o.Lit), Right: r, Token: o}, nil
+	return &InfixExpression{Left: l, Operator: string(o.Lit), Right: r, Token: o,
+		Span: Span{l.Pos(), r.End()}}, nil
+}
+
+func NewPrefixExpression(oper, right Attrib) (Expression, error) {
+	o, ok := oper.(*token.Token)
+	if !ok {
+		return nil, Error("NewPrefixExpression", "*token.Token", "oper", oper)
+	}
+
+	r, ok := right.(Expression)
+	if !ok {
+		return nil, Error("NewPrefixExpression", "Expression", "right", right)
+	}
+
+	return &PrefixExpression{Operator: string(o.Lit), Right: r, Token: o,
+		Span: Span{tokenSpan(o).From, r.End()}}, nil
 }
 
 func NewIntegerLiteral(integer Attrib) (Expression, error) {
@@ -219,39 +449,119 @@ func NewIntegerLiteral(integer Attrib) (Expression, error) {
 		return nil, Error("NewIntegerLiteral", "*token.Token", "integer", integer)
 	}
 
-	return &IntegerLiteral{Token: intLit, Value: string(intLit.Lit)}, nil
+	return &IntegerLiteral{Token: intLit, Value: string(intLit.Lit),
+		Span: tokenSpan(intLit)}, nil
 }
 
 func NewStringLiteral(str Attrib) (Expression, error) {
-	return &StringLiteral{Value: string(str.(*token.Token).Lit), Token: str.(*token.Token)}, nil
+	t, ok := str.(*token.Token)
+	if !ok {
+		return nil, Error("NewStringLiteral", "*token.Token", "str", str)
+	}
+
+	return &StringLiteral{Value: string(t.Lit), Token: t, Span: tokenSpan(t)}, nil
 }
 
-func NewIdentInit(ident, expr Attrib) (Statement, error) {
+func NewIdentInit(let, ident, expr Attrib) (Statement, error) {
+	l, ok := let.(*token.Token)
+	if !ok {
+		return nil, Error("NewIdentInit", "*token.Token", "let", let)
+	}
+
+	i, ok := ident.(*token.Token)
+	if !ok {
+		return nil, Error("NewIdentInit", "*token.Token", "ident", ident)
+	}
+
 	e, ok := expr.(Expression)
 	if !ok {
 		return nil, Error("NewIdentInit", "Expression", "expr", expr)
 	}
 
-	return &InitStatement{Location: string(ident.(*token.Token).Lit), Token: ident.(*token.Token), Expr: e}, nil
+	return &InitStatement{Location: string(i.Lit), Token: i, Expr: e,
+		Span: Span{tokenSpan(l).From, e.End()}}, nil
+}
+
+// Declaration with an explicit type, and optionally an initial value
+func NewTypedInit(let, ident, kind, expr Attrib) (Statement, error) {
+	l, ok := let.(*token.Token)
+	if !ok {
+		return nil, Error("NewTypedInit", "*token.Token", "let", let)
+	}
+
+	i, ok := ident.(*token.Token)
+	if !ok {
+		return nil, Error("NewTypedInit", "*token.Token", "ident", ident)
+	}
+
+	k, ok := kind.(*token.Token)
+	if !ok {
+		return nil, Error("NewTypedInit", "*token.Token", "kind", kind)
+	}
+
+	node := &InitStatement{Location: string(i.Lit), Type: string(k.Lit), Token: i,
+		Span: Span{tokenSpan(l).From, tokenSpan(k).To}}
+	if expr != nil {
+		e, ok := expr.(Expression)
+		if !ok {
+			return nil, Error("NewTypedInit", "Expression", "expr", expr)
+		}
+		node.Expr, node.Span.To = e, e.End()
+	}
+
+	return node, nil
+}
+
+// Declaration of a constant, whose type may be explicit
+func NewConstInit(tok, ident, kind, expr Attrib) (Statement, error) {
+	var node Statement
+	var err error
+	if kind == nil {
+		node, err = NewIdentInit(tok, ident, expr)
+	} else {
+		node, err = NewTypedInit(tok, ident, kind, expr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	node.(*InitStatement).Const = true
+	return node, nil
 }
 
 func NewIdentExpression(ident Attrib) (*Identifier, error) {
-	return &Identifier{Value: string(ident.(*token.Token).Lit), Token: ident.(*token.Token)}, nil
+	t, ok := ident.(*token.Token)
+	if !ok {
+		return nil, Error("NewIdentExpression", "*token.Token", "ident", ident)
+	}
+
+	return &Identifier{Value: string(t.Lit), Token: t, Span: tokenSpan(t)}, nil
 }
 
 func NewBoolExpression(val Attrib) (Expression, error) {
-	return &Boolean{Value: val.(bool)}, nil
+	t, ok := val.(*token.Token)
+	if !ok {
+		return nil, Error("NewBoolExpression", "*token.Token", "val", val)
+	}
+
+	return &Boolean{Value: string(t.Lit) == "true", Token: t, Span: tokenSpan(t)}, nil
 }
 
-func NewReturnStatement(exp Attrib) (Statement, error) {
+func NewReturnStatement(ret, exp Attrib) (Statement, error) {
+	r, ok := ret.(*token.Token)
+	if !ok {
+		return nil, Error("NewReturnExpression", "*token.Token", "ret", ret)
+	}
+
 	e, ok := exp.(Expression)
 	if !ok {
 		return nil, Error("NewReturnExpression", "Expression", "exp", exp)
 	}
-	return &ReturnStatement{ReturnValue: e}, nil
+	return &ReturnStatement{ReturnValue: e, Token: r,
+		Span: Span{tokenSpan(r).From, e.End()}}, nil
 }
 
-func NewFunctionCall(name, args Attrib) (Expression, error) {
+func NewFunctionCall(name, args, rparen Attrib) (Expression, error) {
 	n, ok := name.(*token.Token)
 	if !ok {
 		return nil, fmt.Errorf("invalid type of name. got=%T", name)
@@ -266,7 +576,13 @@ func NewFunctionCall(name, args Attrib) (Expression, error) {
 		}
 	}
 
-	return &FunctionCall{Name: string(n.Lit), Args: a, Token: n}, nil
+	r, ok := rparen.(*token.Token)
+	if !ok {
+		return nil, Error("NewFunctionCall", "*token.Token", "rparen", rparen)
+	}
+
+	return &FunctionCall{Name: string(n.Lit), Args: a, Token: n,
+		Span: Span{tokenSpan(n).From, tokenSpan(r).To}}, nil
 }
 
 func NewFormalArg() ([]FormalArg, error) {
@@ -296,6 +612,22 @@ func NewArg() ([]Expression, error) {
 	return []Expression{}, nil
 }
 
+func NewExpressionList(expr Attrib) ([]Expression, error) {
+	e, ok := expr.(Expression)
+	if !ok {
+		return nil, Error("NewExpressionList", "Expression", "expr", expr)
+	}
+	return []Expression{e}, nil
+}
+
+func AppendExpression(exprs, expr Attrib) ([]Expression, error) {
+	e, ok := expr.(Expression)
+	if !ok {
+		return nil, Error("AppendExpression", "Expression", "expr", expr)
+	}
+	return append(exprs.([]Expression), e), nil
+}
+
 func AppendArgs(expr, args Attrib) ([]Expression, error) {
 	as, ok := args.([]Expression)
 	if !ok {
@@ -5,12 +5,15 @@ import "github.com/aniketp/meego/src/token"
 type Attrib interface{}
 
 type Program struct {
+	Span       `json:"-"`
 	Statements []Statement `json:"statements"`
 	Functions  []Statement `json:"functions"`
 }
 
 type Node interface {
 	TokenLiteral() string
+	Pos() Pos // position of the first character of the node
+	End() Pos // position immediately after the node
 }
 
 type Statement interface {
@@ -25,12 +28,14 @@ type Expression interface {
 
 // Statement structures
 type AssignStatement struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Left  Identifier   `json:"left"`
 	Right Expression   `json:"right"`
 }
 
 type FunctionStatement struct {
+	Span       `json:"-"`
 	Token      *token.Token    `json:"-"`
 	Name       string          `json:"name"`
 	Parameters []FormalArg     `json:"params"`
@@ -44,71 +49,129 @@ type FormalArg struct {
 }
 
 type ForStatement struct {
+	Span           `json:"-"`
 	Token          *token.Token    `json:"-"`
+	Init           Statement       `json:"init"`
 	Condition      Expression      `json:"condition"`
+	Post           Statement       `json:"post"`
 	BlockStatement *BlockStatement `json:"block"`
 }
 
 type ReturnStatement struct {
+	Span        `json:"-"`
 	Token       *token.Token `json:"-"`
 	ReturnValue Expression   `json:"return"`
 }
 
+type BreakStatement struct {
+	Span  `json:"-"`
+	Token *token.Token `json:"-"`
+}
+
+type ContinueStatement struct {
+	Span  `json:"-"`
+	Token *token.Token `json:"-"`
+}
+
 type BlockStatement struct {
+	Span       `json:"-"`
 	Token      *token.Token `json:"-"`
 	Statements []Statement  `json:"statements"`
 }
 
 type IfStatement struct {
+	Span        `json:"-"`
 	Token       *token.Token    `json:"-"`
 	Condition   Expression      `json:"condition"`
 	Block       *BlockStatement `json:"block"`
-	Alternative *BlockStatement `json:"alternative"`
+	Alternative Statement       `json:"alternative"` // *BlockStatement, *IfStatement or nil
+}
+
+type SwitchStatement struct {
+	Span  `json:"-"`
+	Token *token.Token  `json:"-"`
+	Value Expression    `json:"value"`
+	Cases []*CaseClause `json:"cases"`
+}
+
+// A case of a switch, or its default if it has no values
+type CaseClause struct {
+	Span   `json:"-"`
+	Token  *token.Token    `json:"-"`
+	Values []Expression    `json:"values"`
+	Body   *BlockStatement `json:"body"`
 }
 
 type ExpressionStatement struct {
+	Span       `json:"-"`
 	Token      *token.Token `json:"-"`
 	Expression Expression   `json:"statement"`
 }
 
 type InitStatement struct {
+	Span     `json:"-"`
 	Token    *token.Token `json:"-"`
-	Expr     Expression   `json:"expression"`
+	Expr     Expression   `json:"expression"` // nil for the zero value of Type
 	Location string       `json:"location"`
+	Type     string       `json:"type,omitempty"` // declared type, empty if inferred
+	Const    bool         `json:"const,omitempty"`
 }
 
 // Expression structures
 type Identifier struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Value string       `json:"value"`
 }
 
 type Boolean struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Value bool         `json:"value"`
 }
 
 type IntegerLiteral struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Value string       `json:"value"`
 }
 
 type StringLiteral struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Value string       `json:"value"`
 }
 
 type InfixExpression struct {
+	Span     `json:"-"`
 	Token    *token.Token `json:"-"`
-	Type     string       `json:"-"`
 	Left     Expression   `json:"left"`
 	Right    Expression   `json:"right"`
 	Operator string       `json:"operator"`
 }
 
+type PrefixExpression struct {
+	Span     `json:"-"`
+	Token    *token.Token `json:"-"`
+	Operator string       `json:"operator"`
+	Right    Expression   `json:"right"`
+}
+
 type FunctionCall struct {
+	Span  `json:"-"`
 	Token *token.Token `json:"-"`
 	Name  string       `json:"name"`
 	Args  []Expression `json:"args"`
-	Type  string       `json:"type"`
+}
+
+// Position in the source, with lines and columns starting at 1
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Source range a node was parsed from
+type Span struct {
+	From Pos
+	To   Pos
 }
@@ -0,0 +1,197 @@
+package checker
+
+import "errors"
+
+// Normalized operations
+const (
+	PLUS   = "PLUS"
+	EQUAL  = "EQ"
+	NEQ    = "NEQ"
+	LT     = "LT"
+	GT     = "GT"
+	LTE    = "LTE"
+	GTE    = "GTE"
+	MINUS  = "MINUS"
+	TIMES  = "TIMES"
+	DIVIDE = "DIVIDE"
+	AND    = "AND"
+	OR     = "OR"
+	NEG    = "NEG"
+	NOT    = "NOT"
+	PRINT  = "PRINT"
+)
+
+/*Operators : binary operators of the grammar, normalized to their methods */
+var Operators = map[string]string{
+	"+":   PLUS,
+	"-":   MINUS,
+	"*":   TIMES,
+	"/":   DIVIDE,
+	"==":  EQUAL,
+	"!=":  NEQ,
+	"<":   LT,
+	">":   GT,
+	"<=":  LTE,
+	">=":  GTE,
+	"and": AND,
+	"or":  OR,
+}
+
+/*Prefixes : unary operators of the grammar, normalized to their methods */
+var Prefixes = map[string]string{
+	"-":   NEG,
+	"!":   NOT,
+	"not": NOT,
+}
+
+/*Builtin : a method of a builtin type */
+type Builtin struct {
+	Method string
+	Signature
+
+	// C++ expression computing the result, in the native form of the type
+	// (int, string or bool). %[1]s is the receiver and %[2]s the argument.
+	// Empty for the methods that the runtime's Base class provides.
+	Cpp string
+
+	// Computes the result on Go values, for the constants folded by the
+	// checker and for the interpreter. Nil for the methods with side effects.
+	Fold Fold
+}
+
+/*Fold : a method on constant int32, string or bool values */
+//
+// Unary methods get a nil argument.
+type Fold func(x, y interface{}) (interface{}, error)
+
+/*BuiltinType : a builtin type along with its methods */
+type BuiltinType struct {
+	Name    string
+	Zero    string // C++ native value of a variable declared without one
+	Methods []Builtin
+}
+
+/*Builtins : specification of the builtin types */
+//
+// This is the one place the methods are defined: the TypeTable is built
+// from it, and so are the C++ runtime (see codegen.Runtime) and the methods
+// of the interpreter (see interp.Methods). Types come in the order the
+// runtime declares them, before any type that uses them.
+var Builtins = []BuiltinType{
+	{BOOL_TYPE, "false", []Builtin{
+		{AND, Signature{BOOL_TYPE, []string{BOOL_TYPE}}, "%[1]s && %[2]s",
+			bools(func(x, y bool) interface{} { return x && y })},
+		{OR, Signature{BOOL_TYPE, []string{BOOL_TYPE}}, "%[1]s || %[2]s",
+			bools(func(x, y bool) interface{} { return x || y })},
+		{EQUAL, Signature{BOOL_TYPE, []string{BOOL_TYPE}}, "%[1]s == %[2]s",
+			bools(func(x, y bool) interface{} { return x == y })},
+		{NEQ, Signature{BOOL_TYPE, []string{BOOL_TYPE}}, "%[1]s != %[2]s",
+			bools(func(x, y bool) interface{} { return x != y })},
+		{NOT, Signature{BOOL_TYPE, []string{}}, "!%[1]s",
+			bools(func(x, _ bool) interface{} { return !x })},
+		{PRINT, Signature{NOTHING_TYPE, []string{}}, "", nil},
+	}},
+	{STRING_TYPE, `""`, []Builtin{
+		{PLUS, Signature{STRING_TYPE, []string{STRING_TYPE}}, "%[1]s + %[2]s",
+			strs(func(x, y string) interface{} { return x + y })},
+		{EQUAL, Signature{BOOL_TYPE, []string{STRING_TYPE}}, "%[1]s == %[2]s",
+			strs(func(x, y string) interface{} { return x == y })},
+		{NEQ, Signature{BOOL_TYPE, []string{STRING_TYPE}}, "%[1]s != %[2]s",
+			strs(func(x, y string) interface{} { return x != y })},
+		{PRINT, Signature{NOTHING_TYPE, []string{}}, "", nil},
+	}},
+	{INT_TYPE, "0", []Builtin{
+		{PLUS, Signature{INT_TYPE, []string{INT_TYPE}}, "%[1]s + %[2]s",
+			ints(func(x, y int32) interface{} { return x + y })},
+		{MINUS, Signature{INT_TYPE, []string{INT_TYPE}}, "%[1]s - %[2]s",
+			ints(func(x, y int32) interface{} { return x - y })},
+		{TIMES, Signature{INT_TYPE, []string{INT_TYPE}}, "%[1]s * %[2]s",
+			ints(func(x, y int32) interface{} { return x * y })},
+		{DIVIDE, Signature{INT_TYPE, []string{INT_TYPE}}, "divide(%[1]s, %[2]s)",
+			divide},
+		{LT, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s < %[2]s",
+			ints(func(x, y int32) interface{} { return x < y })},
+		{GT, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s > %[2]s",
+			ints(func(x, y int32) interface{} { return x > y })},
+		{LTE, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s <= %[2]s",
+			ints(func(x, y int32) interface{} { return x <= y })},
+		{GTE, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s >= %[2]s",
+			ints(func(x, y int32) interface{} { return x >= y })},
+		{EQUAL, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s == %[2]s",
+			ints(func(x, y int32) interface{} { return x == y })},
+		{NEQ, Signature{BOOL_TYPE, []string{INT_TYPE}}, "%[1]s != %[2]s",
+			ints(func(x, y int32) interface{} { return x != y })},
+		{NEG, Signature{INT_TYPE, []string{}}, "-%[1]s",
+			ints(func(x, _ int32) interface{} { return -x })},
+		{PRINT, Signature{NOTHING_TYPE, []string{}}, "", nil},
+	}},
+}
+
+// Folds of the methods on each type, whose argument is missing if unary
+func bools(f func(x, y bool) interface{}) Fold {
+	return func(x, y interface{}) (interface{}, error) {
+		arg, _ := y.(bool)
+		return f(x.(bool), arg), nil
+	}
+}
+
+func strs(f func(x, y string) interface{}) Fold {
+	return func(x, y interface{}) (interface{}, error) {
+		arg, _ := y.(string)
+		return f(x.(string), arg), nil
+	}
+}
+
+func ints(f func(x, y int32) interface{}) Fold {
+	return func(x, y interface{}) (interface{}, error) {
+		arg, _ := y.(int32)
+		return f(x.(int32), arg), nil
+	}
+}
+
+// Integer division, truncated toward zero as in C++
+func divide(x, y interface{}) (interface{}, error) {
+	if y.(int32) == 0 {
+		return nil, errors.New("division by zero")
+	}
+	return x.(int32) / y.(int32), nil
+}
+
+/*TypeTable : Methods defining various types */
+var TypeTable = typeTable(Builtins)
+
+func typeTable(types []BuiltinType) map[string]map[string]Signature {
+	table := map[string]map[string]Signature{}
+	for _, kind := range types {
+		table[kind.Name] = map[string]Signature{}
+		for _, method := range kind.Methods {
+			table[kind.Name][method.Method] = method.Signature
+		}
+	}
+	return table
+}
+
+// Check if variables of a type can be declared without a value
+func HasZero(kind string) bool {
+	for _, builtin := range Builtins {
+		if builtin.Name == kind {
+			return builtin.Zero != ""
+		}
+	}
+	return false
+}
+
+// The specification of a method
+func GetBuiltin(kind, method string) (Builtin, bool) {
+	for _, builtin := range Builtins {
+		if builtin.Name != kind {
+			continue
+		}
+		for _, m := range builtin.Methods {
+			if m.Method == method {
+				return m, true
+			}
+		}
+	}
+	return Builtin{}, false
+}
@@ -1,82 +1,221 @@
 package checker
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/aniketp/meego/src/ast"
 )
 
-//  Driver Type-Checker function
-func Checker(program *ast.Program) error {
-	env = NewEnvironment()
-	_, err := checker(program)
-	return err
+/*Checker : state of a single run of the type checker */
+//
+// Runs share no state, so that programs can be checked concurrently.
+type Checker struct {
+	env         *Environment              // current scope
+	types       map[ast.Expression]string // types of the checked expressions
+	bindings    map[ast.Node]*Binding     // resolved identifiers
+	diagnostics Diagnostics               // problems found so far
+	filename    string                    // name of the file being checked
+	returns     string                    // return type of the current function
 }
 
-func checker(node ast.Node) (string, error) {
+// New checker for a program parsed from file, within env
+func New(file string, env *Environment) *Checker {
+	return &Checker{env: env, types: map[ast.Expression]string{},
+		bindings: map[ast.Node]*Binding{}, filename: file}
+}
+
+// Driver Type-Checker function
+func Check(program *ast.Program) error {
+	_, diags := CheckFile("", program)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	return nil
+}
+
+// Check a program parsed from file, reporting every problem found rather
+// than stopping at the first one
+func CheckFile(file string, program *ast.Program) (*TypeInfo, Diagnostics) {
+	c := New(file, NewEnvironment())
+
+	_, err := c.check(program)
+	if err != nil {
+		c.report(err)
+	}
+
+	return c.result()
+}
+
+// Check a program against e, an environment that outlives it, as lines
+// entered in a REPL are. Functions and top-level declarations are made in e
+// itself, unless the program has errors, in which case e is left untouched.
+func CheckIn(e *Environment, file string, program *ast.Program) (*TypeInfo, Diagnostics) {
+	c := New(file, e)
+
+	saved := e.save()
+	c.evalFunctions(program.Functions)
+	c.evalTopLevel(program.Statements)
+
+	info, diags := c.result()
+	if diags.HasErrors() {
+		e.restore(saved)
+	}
+	return info, diags
+}
+
+// Check an expression within e, which is left untouched
+func CheckExpr(e *Environment, file string, expr ast.Expression) (*TypeInfo, Diagnostics) {
+	c := New(file, e)
+
+	_, err := c.check(expr)
+	if err != nil {
+		c.report(err)
+	}
+
+	return c.result()
+}
+
+// What the run found, with the diagnostics in source order
+func (c *Checker) result() (*TypeInfo, Diagnostics) {
+	sortDiagnostics(c.diagnostics)
+	return &TypeInfo{Types: c.types, Bindings: c.bindings, Funcs: c.env.Funcs},
+		c.diagnostics
+}
+
+// Check a node, recording the type of expressions
+func (c *Checker) check(node ast.Node) (string, error) {
+	kind, err := c.dispatch(node)
+	if expr, ok := node.(ast.Expression); ok && err == nil {
+		c.types[expr] = kind
+	}
+
+	return kind, err
+}
+
+func (c *Checker) dispatch(node ast.Node) (string, error) {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(node)
+		return c.evalProgram(node)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node)
+		return c.evalBlockStatement(node)
 	case *ast.ReturnStatement:
-		return evalReturnStatement(node)
+		return c.evalReturnStatement(node)
 	case *ast.IfStatement:
-		return evalIfStatement(node)
+		return c.evalIfStatement(node)
+	case *ast.ForStatement:
+		return c.evalForStatement(node)
+	case *ast.SwitchStatement:
+		return c.evalSwitchStatement(node)
+	case *ast.BreakStatement:
+		return c.evalBreakStatement(node)
+	case *ast.ContinueStatement:
+		return c.evalContinueStatement(node)
 	case *ast.ExpressionStatement:
-		return evalExpressionStatement(node)
+		return c.evalExpressionStatement(node)
 	case *ast.AssignStatement:
-		return evalAssignStatement(node)
+		return c.evalAssignStatement(node)
 	case *ast.InitStatement:
-		return evalInitStatement(node)
+		return c.evalInitStatement(node)
 	case *ast.FunctionStatement:
-		return evalFunctionStatement(node)
+		return c.evalFunctionStatement(node)
 
 	// Expressions
 	case *ast.InfixExpression:
-		return evalInfixExpression(node)
+		return c.evalInfixExpression(node)
+	case *ast.PrefixExpression:
+		return c.evalPrefixExpression(node)
 	case *ast.IntegerLiteral:
-		return evalInteger(node)
+		return c.evalInteger(node)
 	case *ast.StringLiteral:
-		return evalString(node)
+		return c.evalString(node)
 	case *ast.Boolean:
-		return evalBoolean(node)
+		return c.evalBoolean(node)
 	case *ast.Identifier:
-		return evalIdentifier(node)
+		return c.evalIdentifier(node)
 	case *ast.FunctionCall:
-		return evalFunctionCall(node)
+		return c.evalFunctionCall(node)
 	}
 
 	return "", nil
 }
 
 // Target program
-func evalProgram(p *ast.Program) (string, error) {
-	for _, function := range p.Functions {
-		_, err := checker(function)
+func (c *Checker) evalProgram(p *ast.Program) (string, error) {
+	c.evalFunctions(p.Functions)
+
+	// Top-level statements get a scope of their own, hidden from functions
+	root := c.env
+	c.env = NewEnclosedEnvironment(root)
+	defer func() { c.env = root }()
+
+	c.evalTopLevel(p.Statements)
+	return "", nil
+}
+
+func (c *Checker) evalFunctions(functions []ast.Statement) {
+	// Register every signature before checking any body, so that functions
+	// can call themselves and those declared later in the file
+	for _, function := range functions {
+		node, ok := function.(*ast.FunctionStatement)
+		if !ok {
+			continue
+		}
+
+		if IsBuiltin(node.Name) {
+			c.report(errorAt(node, REDECLARED, "Function redeclares a builtin"))
+			continue
+		}
+
+		if _, ok := c.env.Function(node.Name); ok {
+			c.report(errorAt(node, REDECLARED, "Function already exists"))
+			continue
+		}
+
+		c.env.SetFunction(node.Name, functionSignature(node))
+	}
+
+	for _, function := range functions {
+		_, err := c.check(function)
 		if err != nil {
-			return "", err
+			c.report(err)
 		}
 	}
+}
 
-	for _, statement := range p.Statements {
-		_, err := checker(statement)
+func (c *Checker) evalTopLevel(statements []ast.Statement) {
+	c.env.DeclareLater(statements)
+	for _, statement := range statements {
+		_, err := c.check(statement)
 		if err != nil {
-			return "", err
+			c.report(err)
 		}
 	}
-	return "", nil
 }
 
 // Statements
-func evalBlockStatement(node *ast.BlockStatement) (string, error) {
-	for _, statement := range node.Statements {
-		result, err := checker(statement)
+func (c *Checker) evalBlockStatement(node *ast.BlockStatement) (string, error) {
+	outer := c.env
+	c.env = NewEnclosedEnvironment(outer)
+	defer func() { c.env = outer }()
+
+	return c.evalStatements(node.Statements)
+}
+
+// Check statements within the current scope, returning the type of the
+// first return statement. Errors are reported as they're found, so that a
+// bad statement doesn't stop the rest from being checked.
+func (c *Checker) evalStatements(statements []ast.Statement) (string, error) {
+	c.env.DeclareLater(statements)
+	for _, statement := range statements {
+		result, err := c.check(statement)
 		if err != nil {
-			return "", err
+			c.report(err)
+			result = INVALID_TYPE
 		}
 
 		if reflect.TypeOf(statement) == reflect.TypeOf(&ast.ReturnStatement{}) {
@@ -87,182 +226,475 @@ func evalBlockStatement(node *ast.BlockStatement) (string, error) {
 	return NOTHING_TYPE, nil
 }
 
-func evalReturnStatement(node *ast.ReturnStatement) (string, error) {
-	res, err := checker(node.ReturnValue)
-	return res, err
+// Every return, however deeply nested, must match the enclosing function
+func (c *Checker) evalReturnStatement(node *ast.ReturnStatement) (string, error) {
+	if c.returns == "" {
+		return "", errorAt(node, MISPLACED_BRANCH, "return is not in a function")
+	}
+
+	res, err := c.check(node.ReturnValue)
+	if err != nil {
+		return "", err
+	}
+
+	if res != c.returns && !isInvalid(res) {
+		return "", errorAt(node, TYPE_MISMATCH, "Incorrect return type")
+	}
+	return res, nil
+}
+
+func (c *Checker) evalIfStatement(node *ast.IfStatement) (string, error) {
+	cond, err := c.check(node.Condition)
+	if err != nil {
+		c.report(err)
+	} else if cond != BOOL_TYPE && cond != INVALID_TYPE {
+		c.report(errorAt(node.Condition, TYPE_MISMATCH, "Condition not of Boolean type"))
+	}
+
+	_, err = c.check(node.Block)
+	if err != nil {
+		c.report(err)
+	}
+
+	if node.Alternative != nil {
+		_, err = c.check(node.Alternative)
+		if err != nil {
+			c.report(err)
+		}
+	}
+
+	return "", nil
 }
 
-func evalIfStatement(node *ast.IfStatement) (string, error) {
-	cond, _ := checker(node.Condition)
-	if cond != BOOL_TYPE {
-		return "", errors.New("Condition not of Boolean type")
+func (c *Checker) evalForStatement(node *ast.ForStatement) (string, error) {
+	// The init statement is scoped to the loop
+	outer := c.env
+	c.env = NewEnclosedEnvironment(outer)
+	defer func() { c.env = outer }()
+
+	if node.Init != nil {
+		_, err := c.check(node.Init)
+		if err != nil {
+			c.report(err)
+		}
+	}
+
+	cond, err := c.check(node.Condition)
+	if err != nil {
+		c.report(err)
+	} else if cond != BOOL_TYPE && cond != INVALID_TYPE {
+		c.report(errorAt(node.Condition, TYPE_MISMATCH,
+			"Loop condition not of Boolean type"))
+	}
+
+	if node.Post != nil {
+		_, err := c.check(node.Post)
+		if err != nil {
+			c.report(err)
+		}
+	}
+
+	c.env.Loops++
+	_, err = c.check(node.BlockStatement)
+	if err != nil {
+		c.report(err)
 	}
 
-	checker(node.Block)
-	checker(node.Alternative)
 	return "", nil
 }
 
-func evalExpressionStatement(node *ast.ExpressionStatement) (string, error) {
-	_, err := checker(node.Expression)
+func (c *Checker) evalSwitchStatement(node *ast.SwitchStatement) (string, error) {
+	kind, err := c.check(node.Value)
 	if err != nil {
-		return "", err
+		c.report(err)
+		kind = INVALID_TYPE
+	} else if !isInvalid(kind) && !MethodExist(kind, EQUAL) {
+		c.report(errorAt(node.Value, INVALID_OPERATION,
+			"Cannot switch on a value of type %s", kind))
+		kind = INVALID_TYPE
+	}
+
+	var def *ast.CaseClause
+	seen := map[string]bool{} // constant case values
+	constant := true          // whether every case value is
+	for _, clause := range node.Cases {
+		if clause.Values == nil {
+			if def != nil {
+				c.report(errorAt(clause, DUPLICATE_CASE, "Multiple defaults in switch"))
+			}
+			def = clause
+		}
+
+		for _, value := range clause.Values {
+			constant = c.checkCase(kind, value, seen) && constant
+		}
+
+		// Within a case, break ends the switch
+		outer := c.env
+		c.env = NewEnclosedEnvironment(outer)
+		c.env.Switches++
+		_, err := c.check(clause.Body)
+		c.env = outer
+		if err != nil {
+			c.report(err)
+		}
+	}
+
+	if kind == BOOL_TYPE && def == nil {
+		var missing []string
+		for _, val := range []string{"true", "false"} {
+			if !seen[val] {
+				missing = append(missing, val)
+			}
+		}
+		// Cases that aren't constant may cover what's missing, or not
+		if len(missing) != 0 && constant {
+			c.warn(node, NON_EXHAUSTIVE, "Switch on Bool has no case for %s and no default",
+				strings.Join(missing, " or "))
+		} else if len(missing) != 0 {
+			c.warn(node, NON_EXHAUSTIVE,
+				"Switch on Bool has no default and its cases may not cover both true and false")
+		}
 	}
 
 	return "", nil
 }
 
-func evalInitStatement(node *ast.InitStatement) (string, error) {
-	if env.IdentExist(node.Location) {
-		return "", errors.New("Identifier already exists")
+// Check a case value against the type of the switch, and that it isn't a
+// constant that an earlier case already has. Tells if the value is constant.
+func (c *Checker) checkCase(kind string, value ast.Expression, seen map[string]bool) bool {
+	t, err := c.check(value)
+	if err != nil {
+		c.report(err)
+		return false
+	}
+
+	if t != kind && !isInvalid(t, kind) {
+		c.report(errorAt(value, TYPE_MISMATCH,
+			"Case of type %s in switch on %s", t, kind))
+		return false
 	}
 
-	right, err := checker(node.Expr)
+	folded, err := c.fold(value)
 	if err != nil {
-		return "", nil
+		return false
+	}
+
+	val := fmt.Sprintf("%#v", folded)
+	if seen[val] {
+		c.report(errorAt(value, DUPLICATE_CASE, "Duplicate case %s in switch", val))
+	}
+	seen[val] = true
+	return true
+}
+
+func (c *Checker) evalBreakStatement(node *ast.BreakStatement) (string, error) {
+	if c.env.Loops == 0 && c.env.Switches == 0 {
+		return "", errorAt(node, MISPLACED_BRANCH, "break is not in a loop or switch")
+	}
+
+	return "", nil
+}
+
+func (c *Checker) evalContinueStatement(node *ast.ContinueStatement) (string, error) {
+	if c.env.Loops == 0 {
+		return "", errorAt(node, MISPLACED_BRANCH, "continue is not in a loop")
 	}
 
-	env.Set(node.Location, right) // Set identifier type
 	return "", nil
 }
 
-// TODO: Fix this
-func evalAssignStatement(node *ast.AssignStatement) (string, error) {
-	right, err := checker(node.Right)
+func (c *Checker) evalExpressionStatement(node *ast.ExpressionStatement) (string, error) {
+	_, err := c.check(node.Expression)
 	if err != nil {
-		return "", nil
+		return "", err
+	}
+
+	return "", nil
+}
+
+func (c *Checker) evalInitStatement(node *ast.InitStatement) (string, error) {
+	if c.env.IdentExist(node.Location) {
+		return "", errorAt(node, REDECLARED, "Identifier already exists")
+	}
+
+	// The identifier is declared even if there's an error, to avoid
+	// cascading ones
+	declare := func(kind string) *Binding {
+		binding := c.env.Set(node.Location, kind, node)
+		binding.Const = node.Const
+		c.setBinding(node, binding)
+		return binding
 	}
 
-	if kind, ok := env.Get(node.Left.Value); ok {
-		if kind != right {
-			return "", errors.New("Invalid type assignment")
+	if node.Type != "" && !HasZero(node.Type) {
+		declare(INVALID_TYPE)
+		return "", errorAt(node, UNDEFINED, "Cannot declare a variable of type %s", node.Type)
+	}
+
+	// Without a value, the variable holds the zero value of its type
+	right := node.Type
+	if node.Expr != nil {
+		var err error
+		right, err = c.check(node.Expr)
+		if err != nil {
+			if node.Type == "" {
+				declare(INVALID_TYPE)
+			} else {
+				declare(node.Type)
+			}
+			return "", err
+		}
+	}
+
+	if node.Type != "" && right != node.Type && !isInvalid(right) {
+		declare(node.Type)
+		return "", errorAt(node.Expr, TYPE_MISMATCH,
+			"Cannot use a value of type %s to initialize %s of type %s",
+			right, node.Location, node.Type)
+	}
+
+	binding := declare(right)
+	if node.Const && !isInvalid(right) {
+		val, err := c.fold(node.Expr)
+		if err != nil {
+			return "", err
+		}
+		binding.Value = val
+	}
+	return "", nil
+}
+
+// Evaluate a constant expression at compile time, through the Fold of the
+// methods it calls
+func (c *Checker) fold(expr ast.Expression) (interface{}, error) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		// Out of range literals wrap around, like they do for a C++ int
+		val, _ := strconv.ParseInt(expr.Value, 10, 64)
+		return int32(val), nil
+	case *ast.StringLiteral:
+		return strings.Trim(expr.Value, `"`), nil
+	case *ast.Boolean:
+		return expr.Value, nil
+	case *ast.Identifier:
+		if binding, ok := c.bindings[expr]; ok && binding.Value != nil {
+			return binding.Value, nil
+		}
+	case *ast.InfixExpression:
+		left, err := c.fold(expr.Left)
+		if err != nil {
+			return nil, err
 		}
+		// Both operands of `and` and `or` are folded, even when the left one
+		// decides, so that all of a constant is constant and well-defined
+		right, err := c.fold(expr.Right)
+		if err != nil {
+			return nil, err
+		}
+		return c.foldMethod(expr, expr.Left, Operators[expr.Operator], left, right)
+	case *ast.PrefixExpression:
+		right, err := c.fold(expr.Right)
+		if err != nil {
+			return nil, err
+		}
+		return c.foldMethod(expr, expr.Right, Prefixes[expr.Operator], right, nil)
+	}
+
+	return nil, notConstant(expr)
+}
+
+func notConstant(expr ast.Expression) error {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return errorAt(expr, NOT_CONSTANT, "%s is not a constant", expr.Value)
+	case *ast.FunctionCall:
+		return errorAt(expr, NOT_CONSTANT, "Cannot call %s in a constant", expr.Name)
+	}
+	return errorAt(expr, NOT_CONSTANT, "Value of a constant is not constant")
+}
+
+func (c *Checker) foldMethod(node ast.Node, recv ast.Expression, name string,
+	x, y interface{}) (interface{}, error) {
+	method, ok := GetBuiltin(c.types[recv], name)
+	if !ok || method.Fold == nil {
+		return nil, errorAt(node, NOT_CONSTANT, "Cannot call %s in a constant", name)
+	}
+
+	val, err := method.Fold(x, y)
+	if err != nil {
+		return nil, errorAt(node, INVALID_OPERATION, "Constant %s", err)
+	}
+	return val, nil
+}
+
+func (c *Checker) evalAssignStatement(node *ast.AssignStatement) (string, error) {
+	right, err := c.check(node.Right)
+	if err != nil {
+		return "", err
+	}
+
+	if binding, ok := c.env.Lookup(node.Left.Value); ok {
+		if binding.Const {
+			return "", errorAt(&node.Left, INVALID_OPERATION,
+				"cannot assign to constant %s", node.Left.Value)
+		}
+		if binding.Type != right && !isInvalid(binding.Type, right) {
+			return "", errorAt(node, TYPE_MISMATCH, "Invalid type assignment")
+		}
+		c.setBinding(node, binding)
+	} else if _, ok := c.env.Function(node.Left.Value); ok {
+		return "", errorAt(&node.Left, INVALID_OPERATION,
+			"cannot assign to function %s", node.Left.Value)
 	} else {
-		return "", errors.New("Identifier does not exist")
+		return "", c.undefinedError(&node.Left, node.Left.Value)
 	}
 	return "", nil
 }
 
-func evalFunctionStatement(node *ast.FunctionStatement) (string, error) {
-	var params []string
+func (c *Checker) evalFunctionStatement(node *ast.FunctionStatement) (string, error) {
+	// Parameters share the outermost scope of the function body
+	outer, returns := c.env, c.returns
+	c.env, c.returns = NewEnclosedEnvironment(outer), node.Return
+	defer func() { c.env, c.returns = outer, returns }()
+
 	for _, param := range node.Parameters {
-		env.Set(param.Arg, param.Type) // set params into scope
-		params = append(params, param.Type)
+		if c.env.IdentExist(param.Arg) {
+			c.report(errorAt(node, REDECLARED, "Duplicate parameter %s", param.Arg))
+		}
+		c.env.Set(param.Arg, param.Type, node) // set params into scope
 	}
 
-	res, err := checker(node.Body)
+	res, err := c.evalStatements(node.Body.Statements)
 	if err != nil {
 		return "", err
 	}
 	// check if correct return type
-	if res != node.Return {
-		return "", errors.New("Incorrect return type")
+	if res != node.Return && res != INVALID_TYPE {
+		return "", errorAt(node, TYPE_MISMATCH, "Incorrect return type")
 	}
 
-	SetFunctionSignature(node.Name, Signature{node.Return, params})
 	return "", nil
 }
 
 // Expressions
-func evalFunctionCall(node *ast.FunctionCall) (string, error) {
+func (c *Checker) evalFunctionCall(node *ast.FunctionCall) (string, error) {
 	// print() function call
 	if IsBuiltin(node.Name) {
-		res, err := checker(node.Args[0])
+		if len(node.Args) != 1 {
+			return "", errorAt(node, ARGUMENT_COUNT, "Incorrect number of function arguments")
+		}
+
+		_, err := c.check(node.Args[0])
 		if err != nil {
 			return "", err
 		}
 
-		node.Type = res
 		return NOTHING_TYPE, nil
 	}
 
+	// Variables shadow functions of the same name
+	if binding, ok := c.env.Lookup(node.Name); ok {
+		return "", errorAt(node, INVALID_OPERATION,
+			"cannot call non-function %s (variable of type %s)", node.Name, binding.Type)
+	}
+
 	var sig Signature
 	var ok bool
 	// Check if the function called is a valid one
-	if sig, ok = GetFunctionSignature(node.Name); !ok {
-		return "", errors.New("Function signature does not exist")
+	if sig, ok = c.env.Function(node.Name); !ok {
+		return "", errorAt(node, UNDEFINED, "undefined: %s", node.Name)
 	}
 
 	if len(node.Args) != len(sig.Params) {
-		return "", errors.New("Incorrect number of function arguments")
+		return "", errorAt(node, ARGUMENT_COUNT, "Incorrect number of function arguments")
 	}
 
 	// Validate parameters
 	for i, arg := range node.Args {
-		res, err := checker(arg)
+		res, err := c.check(arg)
 		if err != nil {
-			return "", errors.New(err.Error())
+			return "", err
 		}
 
-		if res != sig.Params[i] {
-			return "", errors.New("Invalid argument type")
+		if res != sig.Params[i] && res != INVALID_TYPE {
+			return "", errorAt(arg, TYPE_MISMATCH, "Invalid argument type")
 		}
 	}
 
 	return sig.Return, nil
 }
 
-func evalIdentifier(node *ast.Identifier) (string, error) {
-	kind, _ := env.Get(node.Value)
-	return kind, nil
+func (c *Checker) evalIdentifier(node *ast.Identifier) (string, error) {
+	binding, ok := c.env.Lookup(node.Value)
+	if !ok {
+		return "", c.undefinedError(node, node.Value)
+	}
+
+	c.setBinding(node, binding)
+	return binding.Type, nil
 }
 
 // Trivial
-func evalBoolean(node *ast.Boolean) (string, error) {
+func (c *Checker) evalBoolean(node *ast.Boolean) (string, error) {
 	return BOOL_TYPE, nil
 }
 
-func evalInteger(node *ast.IntegerLiteral) (string, error) {
+func (c *Checker) evalInteger(node *ast.IntegerLiteral) (string, error) {
 	return INT_TYPE, nil
 }
 
-func evalString(node *ast.StringLiteral) (string, error) {
+func (c *Checker) evalString(node *ast.StringLiteral) (string, error) {
 	return STRING_TYPE, nil
 }
 
 // Evaluate the provided expression in infix form
-func evalInfixExpression(node *ast.InfixExpression) (string, error) {
-	left, err := checker(node.Left)
+func (c *Checker) evalInfixExpression(node *ast.InfixExpression) (string, error) {
+	left, err := c.check(node.Left)
 	if err != nil {
-		return left, err
+		return "", err
 	}
 
-	right, err := checker(node.Right)
+	right, err := c.check(node.Right)
 	if err != nil {
-		return right, err
+		return "", err
+	}
+
+	// Operands that failed to check were already reported
+	if isInvalid(left, right) {
+		return INVALID_TYPE, nil
 	}
 
 	if left != right {
-		return "", errors.New("Incorrect types for operation")
+		return "", errorAt(node, TYPE_MISMATCH, "Incorrect types for operation")
 	}
 
-	// Type setting for code generation
-	node.Type = left // (or right)
+	sig, ok := GetMethod(right, Operators[node.Operator])
+	if !ok {
+		return NOTHING_TYPE, errorAt(node, INVALID_OPERATION,
+			"Method %s does not exist for type %s", Operators[node.Operator], left)
+	}
 
-	// Construct a map for all allowed methods
-	methods := map[string]string{
-		"+":   PLUS,
-		"-":   MINUS,
-		"==":  EQUAL,
-		"<":   LT,
-		">":   GT,
-		"*":   TIMES,
-		"/":   DIVIDE,
-		"or":  OR,
-		"and": AND,
+	return sig.Return, nil
+}
+
+// Evaluate the provided expression in prefix form
+func (c *Checker) evalPrefixExpression(node *ast.PrefixExpression) (string, error) {
+	right, err := c.check(node.Right)
+	if err != nil {
+		return "", err
 	}
 
-	if !MethodExist(right, methods[node.Operator]) {
-		return NOTHING_TYPE, errors.New(fmt.Sprintf("Method %s does not exist for type %s",
-			methods[node.Operator], left))
+	// Operands that failed to check were already reported
+	if isInvalid(right) {
+		return INVALID_TYPE, nil
 	}
 
-	// Locate the node's operator and if found, return BOOL_TYPE
-	for _, opr := range []string{"<=", "<", ">=", ">", "or", "and"} {
-		if node.Operator == opr {
-			return BOOL_TYPE, nil
-		}
+	sig, ok := GetMethod(right, Prefixes[node.Operator])
+	if !ok {
+		return "", errorAt(node, INVALID_OPERATION,
+			"Method %s does not exist for type %s", Prefixes[node.Operator], right)
 	}
 
-	return left, nil
+	return sig.Return, nil
 }
@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aniketp/meego/src/ast"
+)
+
+// Severity of a diagnostic
+const (
+	ERROR   = "error"
+	WARNING = "warning"
+)
+
+// Rule codes, identifying the kind of problem
+const (
+	LEXICAL_ERROR          = "lexical"
+	SYNTAX_ERROR           = "syntax"
+	UNDEFINED              = "undefined"
+	USE_BEFORE_DECLARATION = "use-before-declaration"
+	REDECLARED             = "redeclared"
+	TYPE_MISMATCH          = "type-mismatch"
+	INVALID_OPERATION      = "invalid-operation"
+	ARGUMENT_COUNT         = "argument-count"
+	MISPLACED_BRANCH       = "misplaced-branch"
+	DUPLICATE_CASE         = "duplicate-case"
+	NON_EXHAUSTIVE         = "non-exhaustive"
+	NOT_CONSTANT           = "not-constant"
+	INTERNAL_ERROR         = "internal"
+)
+
+// Every rule code, in the order they're documented
+var Rules = []string{LEXICAL_ERROR, SYNTAX_ERROR, UNDEFINED,
+	USE_BEFORE_DECLARATION, REDECLARED, TYPE_MISMATCH, INVALID_OPERATION,
+	ARGUMENT_COUNT, MISPLACED_BRANCH, DUPLICATE_CASE, NON_EXHAUSTIVE,
+	NOT_CONSTANT, INTERNAL_ERROR}
+
+/*Diagnostic : a problem found within a range of the source */
+type Diagnostic struct {
+	File     string  `json:"file"`
+	Start    ast.Pos `json:"start"`
+	End      ast.Pos `json:"end"`
+	Severity string  `json:"severity"`
+	Code     string  `json:"code"`
+	Message  string  `json:"message"`
+}
+
+// Format as file:line:col: severity: message
+func (d Diagnostic) String() string {
+	var pos []string
+	if d.File != "" {
+		pos = append(pos, d.File)
+	}
+
+	// Some errors, e.g. internal ones, have no position
+	if d.Start.Line > 0 {
+		pos = append(pos, fmt.Sprintf("%d:%d", d.Start.Line, d.Start.Column))
+	}
+
+	pos = append(pos, " "+d.Severity, " "+d.Message)
+	return strings.Join(pos, ":")
+}
+
+/*Diagnostics : every problem found in a program, in source order */
+type Diagnostics []Diagnostic
+
+// Diagnostics are reported as a single error, one problem per line
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Check if any of the diagnostics is an error (rather than a warning)
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == ERROR {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Error found at a node of the program
+type nodeError struct {
+	node ast.Node
+	code string
+	msg  string
+}
+
+func (e *nodeError) Error() string {
+	return e.msg
+}
+
+func errorAt(node ast.Node, code, format string, args ...interface{}) error {
+	return &nodeError{node, code, fmt.Sprintf(format, args...)}
+}
+
+// Record err as a diagnostic and carry on checking
+func (c *Checker) report(err error) {
+	c.addDiagnostic(ERROR, err)
+}
+
+func (c *Checker) warn(node ast.Node, code, format string, args ...interface{}) {
+	c.addDiagnostic(WARNING, errorAt(node, code, format, args...))
+}
+
+func (c *Checker) addDiagnostic(severity string, err error) {
+	diag := Diagnostic{File: c.filename, Severity: severity,
+		Code: INTERNAL_ERROR, Message: err.Error()}
+	if e, ok := err.(*nodeError); ok {
+		diag.Code = e.code
+		if e.node != nil {
+			diag.Start, diag.End = e.node.Pos(), e.node.End()
+		}
+	}
+
+	c.diagnostics = append(c.diagnostics, diag)
+}
+
+// Sort the diagnostics by their position in the source
+func sortDiagnostics(d Diagnostics) {
+	sort.SliceStable(d, func(i, j int) bool {
+		if d[i].Start.Line != d[j].Start.Line {
+			return d[i].Start.Line < d[j].Start.Line
+		}
+		return d[i].Start.Column < d[j].Start.Column
+	})
+}
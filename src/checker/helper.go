@@ -1,17 +1,7 @@
 package checker
 
-// Normalized operations
-const (
-	PLUS   = "PLUS"
-	EQUAL  = "EQ"
-	LT     = "LT"
-	GT     = "GT"
-	MINUS  = "MINUS"
-	TIMES  = "TIMES"
-	DIVIDE = "DIVIDE"
-	AND    = "AND"
-	OR     = "OR"
-	PRINT  = "PRINT"
+import (
+	"github.com/aniketp/meego/src/ast"
 )
 
 // Variable types
@@ -20,6 +10,7 @@ const (
 	STRING_TYPE  = "String"
 	BOOL_TYPE    = "Bool"
 	NOTHING_TYPE = "Nothing"
+	INVALID_TYPE = "Invalid" // expression that failed to check
 )
 
 // Signature of return values
@@ -29,46 +20,115 @@ type Signature struct {
 	Params []string
 }
 
-/*TypeTable : Methods defining various types */
-var TypeTable = map[string]map[string]Signature{
-	INT_TYPE: {
-		PLUS:  {INT_TYPE, []string{INT_TYPE}},
-		MINUS: {INT_TYPE, []string{INT_TYPE}},
-		TIMES: {INT_TYPE, []string{INT_TYPE}},
-		LT:    {BOOL_TYPE, []string{INT_TYPE}},
-		GT:    {BOOL_TYPE, []string{INT_TYPE}},
-		EQUAL: {BOOL_TYPE, []string{INT_TYPE}},
-		PRINT: {NOTHING_TYPE, []string{}},
-	},
-	STRING_TYPE: {
-		PLUS:  {STRING_TYPE, []string{STRING_TYPE}},
-		PRINT: {NOTHING_TYPE, []string{}}},
-
-	BOOL_TYPE: {
-		AND:   {BOOL_TYPE, []string{BOOL_TYPE}},
-		OR:    {BOOL_TYPE, []string{BOOL_TYPE}},
-		PRINT: {NOTHING_TYPE, []string{}}},
-}
-
-/*Environment structure */
-type Environment struct {
-	Vals  map[string]string    // map identifier to type
-	Funcs map[string]Signature // map function name to return type
-	Types map[string]bool      // track valid types
+/*TypeInfo : what the checker resolved in a program */
+//
+// This is how later stages (code generation, evaluation, tools) learn about
+// types, rather than through state hidden in the checker or the AST.
+type TypeInfo struct {
+	Types    map[ast.Expression]string // type of every checked expression
+	Bindings map[ast.Node]*Binding     // identifiers, inits and assignments
+	Funcs    map[string]Signature      // signature of every function
 }
 
-var env Environment
+/*Binding : declaration an identifier resolves to */
+type Binding struct {
+	Name  string
+	Type  string
+	Decl  ast.Node    // InitStatement, or FunctionStatement for a parameter
+	Const bool        // can't be assigned to
+	Value interface{} // folded value of a constant, int32, string or bool
+}
+
+/*Environment structure : a single lexical scope */
+//
+// Scopes are chained through Outer: the program scope holds top-level
+// declarations, each function gets a scope for its parameters and body, and
+// every block (if/else branches, loop bodies) and loop init opens a new one.
+// A name may only be declared once per scope, but may shadow a declaration
+// of an enclosing scope until the end of the block. Functions are enclosed
+// by the root scope only, so they don't see top-level declarations.
+type Environment struct {
+	Vals     map[string]*Binding  // map identifier to its binding
+	Funcs    map[string]Signature // map function name to return type
+	Types    map[string]bool      // track valid types
+	Later    map[string]bool      // names declared further down the scope
+	Loops    int                  // depth of enclosing loops
+	Switches int                  // depth of enclosing switches
+	Outer    *Environment         // enclosing scope
+}
 
 /*IsBuiltin checks for a built-in function (command) */
 func IsBuiltin(name string) bool {
 	return name == "PRINT"
 }
 
-func NewEnvironment() Environment {
-	return Environment{Vals: map[string]string{},
+func NewEnvironment() *Environment {
+	return &Environment{Vals: map[string]*Binding{}, Later: map[string]bool{},
 		Funcs: map[string]Signature{}, Types: map[string]bool{}}
 }
 
+// Open a new scope within outer. Functions and types are shared by all
+// scopes, while the loop and switch depths are inherited.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	return &Environment{Vals: map[string]*Binding{}, Later: map[string]bool{},
+		Funcs: outer.Funcs, Types: outer.Types, Loops: outer.Loops,
+		Switches: outer.Switches, Outer: outer}
+}
+
+// Copy of the declarations of a scope, to undo those of a bad REPL entry
+type snapshot struct {
+	vals  map[string]*Binding
+	funcs map[string]Signature
+}
+
+func (e *Environment) save() snapshot {
+	saved := snapshot{map[string]*Binding{}, map[string]Signature{}}
+	for name, binding := range e.Vals {
+		saved.vals[name] = binding
+	}
+	for name, sig := range e.Funcs {
+		saved.funcs[name] = sig
+	}
+	return saved
+}
+
+/*Snapshot : declarations of a scope at some point */
+type Snapshot struct {
+	saved snapshot
+}
+
+// Save the declarations of the scope, e.g. to undo those of a REPL entry
+// that fails at runtime after checking
+func (e *Environment) Save() Snapshot {
+	return Snapshot{e.save()}
+}
+
+// Undo the declarations made since the snapshot was saved
+func (e *Environment) Restore(s Snapshot) {
+	e.restore(s.saved)
+}
+
+// Restore the scope in place, as enclosed scopes share its maps
+func (e *Environment) restore(saved snapshot) {
+	for name := range e.Vals {
+		delete(e.Vals, name)
+	}
+	for name, binding := range saved.vals {
+		e.Vals[name] = binding
+	}
+
+	for name := range e.Funcs {
+		delete(e.Funcs, name)
+	}
+	for name, sig := range saved.funcs {
+		e.Funcs[name] = sig
+	}
+
+	for name := range e.Later {
+		delete(e.Later, name)
+	}
+}
+
 // Check if the method in the typetable exists
 func MethodExist(kind, method string) bool {
 	methods, ok := TypeTable[kind]
@@ -90,32 +150,142 @@ func GetMethod(kind, method string) (Signature, bool) {
 	return sig, ok
 }
 
-func (e *Environment) Set(name, kind string) {
-	e.Vals[name] = kind
+// Declare name in the current scope
+func (e *Environment) Set(name, kind string, decl ast.Node) *Binding {
+	binding := &Binding{Name: name, Type: kind, Decl: decl}
+	e.Vals[name] = binding
+	delete(e.Later, name)
+	return binding
+}
+
+// Note the names that statements of the current scope will declare, to tell
+// a use before declaration apart from an undefined name
+func (e *Environment) DeclareLater(statements []ast.Statement) {
+	for _, statement := range statements {
+		if init, ok := statement.(*ast.InitStatement); ok {
+			e.Later[init.Location] = true
+		}
+	}
+}
+
+// Check if name is yet to be declared in the current or an enclosing scope
+func (e *Environment) IsLater(name string) bool {
+	for scope := e; scope != nil; scope = scope.Outer {
+		if _, ok := scope.Vals[name]; ok {
+			return false
+		}
+
+		if scope.Later[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *Environment) SetFunction(name string, sig Signature) {
+	e.Funcs[name] = sig
 }
 
-func SetFunctionSignature(name string, sig Signature) {
-	env.Funcs[name] = sig
+// Build the signature of a function from its declaration
+func functionSignature(node *ast.FunctionStatement) Signature {
+	var params []string
+	for _, param := range node.Parameters {
+		params = append(params, param.Type)
+	}
+
+	return Signature{node.Return, params}
+}
+
+// Signature of the function called name
+func (e *Environment) Function(name string) (Signature, bool) {
+	sig, ok := e.Funcs[name]
+	return sig, ok
 }
 
-func GetFunctionSignature(name string) (Signature, bool) {
-	kind, ok := env.Funcs[name]
-	return kind, ok
+// Resolve name to the binding of the innermost scope declaring it
+func (e *Environment) Lookup(name string) (*Binding, bool) {
+	for scope := e; scope != nil; scope = scope.Outer {
+		if binding, ok := scope.Vals[name]; ok {
+			return binding, true
+		}
+	}
+
+	return nil, false
 }
 
 func (e *Environment) Get(name string) (string, bool) {
-	kind, ok := e.Vals[name]
-	return kind, ok
+	binding, ok := e.Lookup(name)
+	if !ok {
+		return "", false
+	}
+
+	return binding.Type, true
 }
 
+// Check if the identifier is declared in the current scope
 func (e *Environment) IdentExist(kind string) bool {
 	_, ok := e.Vals[kind]
 	return ok
 }
 
-func GetIdentType(name string) (string, bool) {
-	kind, ok := env.Vals[name]
-	return kind, ok
+// Explain why name, referenced by node, doesn't resolve to a variable
+func (c *Checker) undefinedError(node ast.Node, name string) error {
+	if c.env.IsLater(name) {
+		return errorAt(node, USE_BEFORE_DECLARATION,
+			"%s used before declaration", name)
+	}
+
+	if _, ok := c.env.Function(name); ok {
+		return errorAt(node, INVALID_OPERATION, "cannot use function %s as a value", name)
+	}
+
+	return errorAt(node, UNDEFINED, "undefined: %s", name)
+}
+
+// Check if any of the types belongs to an expression that failed to check
+func isInvalid(kinds ...string) bool {
+	for _, kind := range kinds {
+		if kind == INVALID_TYPE {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Record the binding a node resolved to
+func (c *Checker) setBinding(node ast.Node, binding *Binding) {
+	c.bindings[node] = binding
+}
+
+/*Binding returns the binding a checked node resolved to */
+func (info *TypeInfo) Binding(node ast.Node) (*Binding, bool) {
+	binding, ok := info.Bindings[node]
+	return binding, ok
+}
+
+func NewTypeInfo() *TypeInfo {
+	return &TypeInfo{Types: map[ast.Expression]string{},
+		Bindings: map[ast.Node]*Binding{}, Funcs: map[string]Signature{}}
+}
+
+/*Merge adds what was resolved in another program, e.g. a later REPL entry */
+func (info *TypeInfo) Merge(other *TypeInfo) {
+	for expr, kind := range other.Types {
+		info.Types[expr] = kind
+	}
+	for node, binding := range other.Bindings {
+		info.Bindings[node] = binding
+	}
+	for name, sig := range other.Funcs {
+		info.Funcs[name] = sig
+	}
+}
+
+/*TypeOf returns the type of a checked expression */
+func (info *TypeInfo) TypeOf(expr ast.Expression) string {
+	return info.Types[expr]
 }
 
 func (e *Environment) TypeExist(kind string) bool {
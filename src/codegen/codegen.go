@@ -2,73 +2,110 @@ package codegen
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/aniketp/meego/src/ast"
 	"github.com/aniketp/meego/src/checker"
 )
 
-func GenWrapper(p *ast.Program) bytes.Buffer {
-	TMP_COUNT = 0
+/*Generator : state of a single run of the code generator */
+//
+// Runs share no state, so that programs can be compiled concurrently.
+type Generator struct {
+	info       *checker.TypeInfo // what the checker resolved in the program
+	tmpCount   int
+	labelCount int
+	loops      []*loopLabel // enclosing loops, innermost last
+	breaks     []*loopLabel // enclosing loops and switches, innermost last
+}
+
+func New(info *checker.TypeInfo) *Generator {
+	return &Generator{info: info}
+}
+
+// Generate the C++ of a program, given what the checker resolved in it
+func GenWrapper(p *ast.Program, info *checker.TypeInfo) bytes.Buffer {
 	var b bytes.Buffer
 
 	// Call the general code-generator method
-	codeGen(p, &b)
+	New(info).codeGen(p, &b)
 	return b
 }
 
 // Core dispatcher function of code-generator
-func codeGen(node ast.Node, b *bytes.Buffer) string {
+func (g *Generator) codeGen(node ast.Node, b *bytes.Buffer) string {
 	switch node := node.(type) {
 	// Statement cases
 	case *ast.Program:
-		return genProgram(node, b)
+		return g.genProgram(node, b)
 	case *ast.BlockStatement:
-		return genBlockStatement(node, b)
+		return g.genBlockStatement(node, b)
 	case *ast.ReturnStatement:
-		return genReturnStatement(node, b)
+		return g.genReturnStatement(node, b)
 	case *ast.FunctionStatement:
-		return genFunctionStatement(node, b)
+		return g.genFunctionStatement(node, b)
 	case *ast.IfStatement:
-		return genIfStatement(node, b)
+		return g.genIfStatement(node, b)
+	case *ast.ForStatement:
+		return g.genForStatement(node, b)
+	case *ast.SwitchStatement:
+		return g.genSwitchStatement(node, b)
+	case *ast.BreakStatement:
+		return g.genBreakStatement(node, b)
+	case *ast.ContinueStatement:
+		return g.genContinueStatement(node, b)
 	case *ast.ExpressionStatement:
-		return genExpressionStatement(node, b)
+		return g.genExpressionStatement(node, b)
 	case *ast.AssignStatement:
-		return genAssignStatement(node, b)
+		return g.genAssignStatement(node, b)
 	case *ast.InitStatement:
-		return genInitStatement(node, b)
+		return g.genInitStatement(node, b)
 	// Expression cases
 	case *ast.InfixExpression:
-		return genInfixExpression(node, b)
+		return g.genInfixExpression(node, b)
+	case *ast.PrefixExpression:
+		return g.genPrefixExpression(node, b)
 	case *ast.IntegerLiteral:
-		return genInteger(node, b)
+		return g.genInteger(node, b)
 	case *ast.StringLiteral:
-		return genString(node, b)
+		return g.genString(node, b)
 	case *ast.Boolean:
-		return genBoolean(node, b)
+		return g.genBoolean(node, b)
 	case *ast.Identifier:
-		return genIdentifier(node, b)
+		return g.genIdentifier(node, b)
 	case *ast.FunctionCall:
-		return genFunctionCall(node, b)
+		return g.genFunctionCall(node, b)
 	}
 
 	return ""
 }
 
 // Generate main program
-func genProgram(node *ast.Program, b *bytes.Buffer) string {
+func (g *Generator) genProgram(node *ast.Program, b *bytes.Buffer) string {
 	write(b,
 		"#include <iostream>\n#include <string>\n#include \"Builtins.cpp\"\n\n")
 
+	// Forward declare every function, so that they can call each other
+	// regardless of their order in the source
+	for _, funcs := range node.Functions {
+		if fn, ok := funcs.(*ast.FunctionStatement); ok {
+			g.genSignature(fn, b)
+			write(b, ";\n")
+		}
+	}
+	write(b, "\n")
+
 	// We'll generate all functions before main, to ensure function
 	// declaration before invocation
 	for _, funcs := range node.Functions {
-		codeGen(funcs, b)
+		g.codeGen(funcs, b)
 	}
 
 	write(b, "int main() {\n")
 	for _, stmt := range node.Statements {
-		codeGen(stmt, b)
+		g.codeGen(stmt, b)
 	}
 
 	// Here, we're done with the main function
@@ -77,44 +114,67 @@ func genProgram(node *ast.Program, b *bytes.Buffer) string {
 }
 
 // Statements within main function
-func genBlockStatement(node *ast.BlockStatement, b *bytes.Buffer) string {
+func (g *Generator) genBlockStatement(node *ast.BlockStatement, b *bytes.Buffer) string {
 	for _, stmt := range node.Statements {
-		codeGen(stmt, b)
+		g.codeGen(stmt, b)
 	}
 	return ""
 }
 
-func genExpressionStatement(node *ast.ExpressionStatement, b *bytes.Buffer) string {
-	expr := codeGen(node.Expression, b)
+func (g *Generator) genExpressionStatement(node *ast.ExpressionStatement, b *bytes.Buffer) string {
+	expr := g.codeGen(node.Expression, b)
 	write(b, "%s;\n", expr)
 	return ""
 }
 
-func genAssignStatement(node *ast.AssignStatement, b *bytes.Buffer) string {
-	right := codeGen(node.Right, b)
+func (g *Generator) genAssignStatement(node *ast.AssignStatement, b *bytes.Buffer) string {
+	right := g.codeGen(node.Right, b)
 	write(b, "%s = %s;\n", node.Left.Value, right)
 	return ""
 }
 
-func genInitStatement(node *ast.InitStatement, b *bytes.Buffer) string {
-	right := codeGen(node.Expr, b)
-	kind, _ := checker.GetIdentType(node.Location)
-	write(b, "%s %s = %s;\n", kind, node.Location, right)
+func (g *Generator) genInitStatement(node *ast.InitStatement, b *bytes.Buffer) string {
+	binding, _ := g.info.Binding(node)
+
+	// Constants are folded into their uses instead
+	if binding.Value != nil {
+		return ""
+	}
+
+	// The default constructor makes the zero value
+	if node.Expr == nil {
+		write(b, "%s %s;\n", binding.Type, node.Location)
+		return ""
+	}
+
+	right := g.codeGen(node.Expr, b)
+	write(b, "%s %s = %s;\n", binding.Type, node.Location, right)
 	return ""
 }
 
-func genReturnStatement(node *ast.ReturnStatement, b *bytes.Buffer) string {
-	value := codeGen(node.ReturnValue, b)
+func (g *Generator) genReturnStatement(node *ast.ReturnStatement, b *bytes.Buffer) string {
+	value := g.codeGen(node.ReturnValue, b)
 	write(b, "return %s;\n", value)
 	return ""
 }
 
 // Generate function body
-func genFunctionStatement(node *ast.FunctionStatement, b *bytes.Buffer) string {
+func (g *Generator) genFunctionStatement(node *ast.FunctionStatement, b *bytes.Buffer) string {
 	if checker.IsBuiltin(node.Name) {
 		panic("Already a builtin function")
 	}
 
+	g.genSignature(node, b)
+
+	// Generate function body
+	write(b, " {\n")
+	g.codeGen(node.Body, b)
+	write(b, "}\n\n")
+	return ""
+}
+
+// Generate the return type, name and arguments of a function
+func (g *Generator) genSignature(node *ast.FunctionStatement, b *bytes.Buffer) {
 	write(b, "%s %s(", node.Return, node.Name)
 
 	// Generate all the arguments
@@ -126,32 +186,181 @@ func genFunctionStatement(node *ast.FunctionStatement, b *bytes.Buffer) string {
 		}
 	}
 
-	// Generate function body
-	write(b, ") {\n")
-	codeGen(node.Body, b)
-	write(b, "}\n\n")
-	return ""
+	write(b, ")")
 }
 
-func genIfStatement(node *ast.IfStatement, b *bytes.Buffer) string {
-	cond := codeGen(node.Condition, b)
+func (g *Generator) genIfStatement(node *ast.IfStatement, b *bytes.Buffer) string {
+	cond := g.codeGen(node.Condition, b)
 	write(b, "if (\"true\" == %s.val) {\n", cond)
-	codeGen(node.Block, b)
-	write(b, "} else {\n")
-	codeGen(node.Alternative, b)
+	g.codeGen(node.Block, b)
+
+	// An else if chain stays flat, as it was written
+	for alt := node.Alternative; alt != nil; {
+		switch next := alt.(type) {
+		case *ast.IfStatement:
+			write(b, "} else if (\"true\" == %s.val) {\n", g.genCondition(next.Condition))
+			g.codeGen(next.Block, b)
+			alt = next.Alternative
+		default:
+			write(b, "} else {\n")
+			g.codeGen(next, b)
+			alt = nil
+		}
+	}
+
 	write(b, "}\n\n")
 	return ""
 }
 
+// Condition of an else if. Unlike the first condition of the chain, its
+// temporaries can't be computed ahead of the if, as they must only be
+// evaluated once the conditions before it failed, so they go in a lambda.
+func (g *Generator) genCondition(cond ast.Expression) string {
+	var code bytes.Buffer
+	res := g.codeGen(cond, &code)
+	if code.Len() == 0 {
+		return res
+	}
+	return lambda(code.String(), res)
+}
+
+// Lambda computing a Bool, called in place
+func lambda(code, res string) string {
+	return fmt.Sprintf("[&]() -> Bool {\n%sreturn %s;\n}()", code, res)
+}
+
+// A switch is lowered to an if chain, comparing its value (computed once)
+// with the values of each case through EQ, in order. A break jumps past it.
+func (g *Generator) genSwitchStatement(node *ast.SwitchStatement, b *bytes.Buffer) string {
+	write(b, "{\n")
+	res := g.codeGen(node.Value, b)
+	value := g.freshTemp()
+	write(b, "%s %s = %s;\n", g.info.TypeOf(node.Value), value, res)
+
+	label := &loopLabel{name: g.freshLabel("break")}
+	g.breaks = append(g.breaks, label)
+
+	// The default is taken when no case matches, wherever it's written
+	var def *ast.CaseClause
+	keyword := "if"
+	for _, clause := range node.Cases {
+		if clause.Values == nil {
+			def = clause
+			continue
+		}
+
+		write(b, "%s (\"true\" == %s.val) {\n", keyword, g.genCase(value, clause.Values))
+		g.codeGen(clause.Body, b)
+		write(b, "}")
+		keyword = " else if"
+	}
+
+	if def != nil {
+		if keyword != "if" {
+			write(b, " else ")
+		}
+		write(b, "{\n")
+		g.codeGen(def.Body, b)
+		write(b, "}")
+	}
+
+	g.breaks = g.breaks[:len(g.breaks)-1]
+	write(b, "\n}\n")
+	if label.used {
+		write(b, "%s:;\n", label.name)
+	}
+	write(b, "\n")
+	return ""
+}
+
+// Condition of a case, true if any of its values equals the value of the
+// switch. Values are only computed until one matches.
+func (g *Generator) genCase(value string, values []ast.Expression) string {
+	var code bytes.Buffer
+	match := g.freshTemp()
+	write(&code, "Bool %s = Bool(\"false\");\n", match)
+	for _, val := range values {
+		write(&code, "if (\"true\" != %s.val) {\n", match)
+		res := g.codeGen(val, &code)
+		write(&code, "%s = %s.%s(%s);\n}\n", match, value, checker.EQUAL, res)
+	}
+
+	return lambda(code.String(), match)
+}
+
+// Loops are lowered to a C++ while, with the condition's temporaries
+// computed inside the loop so that they're reevaluated on every iteration
+func (g *Generator) genForStatement(node *ast.ForStatement, b *bytes.Buffer) string {
+	// Scope the init statement to the loop
+	write(b, "{\n")
+	if node.Init != nil {
+		g.codeGen(node.Init, b)
+	}
+
+	write(b, "while (true) {\n")
+	cond := g.codeGen(node.Condition, b)
+	write(b, "if (\"true\" != %s.val) {\nbreak;\n}\n", cond)
+
+	// A continue jumps past the body (in its own scope, so that the jump
+	// doesn't cross any initialization) straight to the post statement
+	label := &loopLabel{}
+	if node.Post != nil {
+		label.name = g.freshLabel("continue")
+	}
+
+	g.loops = append(g.loops, label)
+	g.breaks = append(g.breaks, &loopLabel{})
+	write(b, "{\n")
+	g.codeGen(node.BlockStatement, b)
+	write(b, "}\n")
+	g.loops = g.loops[:len(g.loops)-1]
+	g.breaks = g.breaks[:len(g.breaks)-1]
+
+	if label.used {
+		write(b, "%s:;\n", label.name)
+	}
+
+	if node.Post != nil {
+		g.codeGen(node.Post, b)
+	}
+
+	write(b, "}\n}\n\n")
+	return ""
+}
+
+func (g *Generator) genBreakStatement(node *ast.BreakStatement, b *bytes.Buffer) string {
+	label := g.breaks[len(g.breaks)-1]
+	if label.name == "" {
+		write(b, "break;\n")
+		return ""
+	}
+
+	label.used = true
+	write(b, "goto %s;\n", label.name)
+	return ""
+}
+
+func (g *Generator) genContinueStatement(node *ast.ContinueStatement, b *bytes.Buffer) string {
+	label := g.loops[len(g.loops)-1]
+	if label.name == "" {
+		write(b, "continue;\n")
+		return ""
+	}
+
+	label.used = true
+	write(b, "goto %s;\n", label.name)
+	return ""
+}
+
 // Generate integers, strings and booleans
-func genInteger(node *ast.IntegerLiteral, b *bytes.Buffer) string {
-	tmpVar := freshTemp()
+func (g *Generator) genInteger(node *ast.IntegerLiteral, b *bytes.Buffer) string {
+	tmpVar := g.freshTemp()
 	write(b, "Int %s = Int(%s);\n", tmpVar, string(node.Token.Lit))
 	return tmpVar
 }
 
-func genString(node *ast.StringLiteral, b *bytes.Buffer) string {
-	tmpVar := freshTemp()
+func (g *Generator) genString(node *ast.StringLiteral, b *bytes.Buffer) string {
+	tmpVar := g.freshTemp()
 	str := string(node.Token.Lit)
 	str = strings.Replace(str, `\`, "\\", -1)
 
@@ -160,7 +369,7 @@ func genString(node *ast.StringLiteral, b *bytes.Buffer) string {
 	return tmpVar
 }
 
-func genBoolean(node *ast.Boolean, b *bytes.Buffer) string {
+func (g *Generator) genBoolean(node *ast.Boolean, b *bytes.Buffer) string {
 	// Node can be either of the boolean values
 	if node.Value {
 		return "Bool(\"true\")"
@@ -169,47 +378,92 @@ func genBoolean(node *ast.Boolean, b *bytes.Buffer) string {
 	}
 }
 
-func genIdentifier(node *ast.Identifier, b *bytes.Buffer) string {
+func (g *Generator) genIdentifier(node *ast.Identifier, b *bytes.Buffer) string {
+	if binding, ok := g.info.Binding(node); ok && binding.Value != nil {
+		return folded(binding.Value)
+	}
 	return node.Value
 }
 
+// C++ value of a constant folded by the checker
+func folded(val interface{}) string {
+	switch val := val.(type) {
+	case int32:
+		return fmt.Sprintf("Int(%d)", val)
+	case string:
+		return fmt.Sprintf("String(%s)", strconv.Quote(val))
+	case bool:
+		return fmt.Sprintf("Bool(\"%t\")", val)
+	}
+
+	panic("not a constant value")
+}
+
 // Evaluate function call and infix expression
-func genInfixExpression(node *ast.InfixExpression, b *bytes.Buffer) string {
-	left := codeGen(node.Left, b)
-	right := codeGen(node.Right, b)
-	kind := node.Type
-
-	tmp := freshTemp()
-	methods := map[string]string{"+": checker.PLUS, "-": checker.MINUS,
-		"==": checker.EQUAL, "<": checker.LT, ">": checker.GT,
-		"*": checker.TIMES, "/": checker.DIVIDE, "or": checker.OR,
-		"and": checker.AND}
-
-	method, _ := checker.GetMethod(kind, methods[node.Operator])
-	write(b, "%s %s = %s.%s(%s);\n", method.Return, tmp, left,
-		methods[node.Operator], right)
+func (g *Generator) genInfixExpression(node *ast.InfixExpression, b *bytes.Buffer) string {
+	if node.Operator == "and" || node.Operator == "or" {
+		return g.genLogicalExpression(node, b)
+	}
+
+	left := g.codeGen(node.Left, b)
+	right := g.codeGen(node.Right, b)
+	kind := g.info.TypeOf(node.Left)
+
+	tmp := g.freshTemp()
+	name := checker.Operators[node.Operator]
+	method, _ := checker.GetMethod(kind, name)
+	write(b, "%s %s = %s.%s(%s);\n", method.Return, tmp, left, name, right)
+	return tmp
+}
+
+// Generate `and` and `or`, which only evaluate their right operand when
+// the left one doesn't decide the result
+func (g *Generator) genLogicalExpression(node *ast.InfixExpression, b *bytes.Buffer) string {
+	tmp := g.freshTemp()
+	write(b, "Bool %s = %s;\n", tmp, g.codeGen(node.Left, b))
+
+	// `and` goes on if the left is true, `or` if it's false
+	decided := "False"
+	if node.Operator == "or" {
+		decided = "True"
+	}
+
+	write(b, "if (%s.val != %s) {\n", tmp, decided)
+	write(b, "%s = %s;\n", tmp, g.codeGen(node.Right, b))
+	write(b, "}\n")
+	return tmp
+}
+
+func (g *Generator) genPrefixExpression(node *ast.PrefixExpression, b *bytes.Buffer) string {
+	right := g.codeGen(node.Right, b)
+	kind := g.info.TypeOf(node.Right)
+
+	tmp := g.freshTemp()
+	name := checker.Prefixes[node.Operator]
+	method, _ := checker.GetMethod(kind, name)
+	write(b, "%s %s = %s.%s();\n", method.Return, tmp, right, name)
 	return tmp
 }
 
-func genFunctionCall(node *ast.FunctionCall, b *bytes.Buffer) string {
+func (g *Generator) genFunctionCall(node *ast.FunctionCall, b *bytes.Buffer) string {
 	var sig checker.Signature
 	args := make([]string, len(node.Args))
 	// store expression tmp vars
 	for i, arg := range node.Args {
-		res := codeGen(arg, b)
+		res := g.codeGen(arg, b)
 		args[i] = res
 	}
 
-	tmp := freshTemp()
+	tmp := g.freshTemp()
 	if checker.IsBuiltin(node.Name) {
-		sig, ok := checker.GetMethod(node.Type, node.Name)
+		sig, ok := checker.GetMethod(g.info.TypeOf(node.Args[0]), node.Name)
 		if !ok {
 			panic("No builtin function")
 		}
 
 		write(b, "%s %s = %s.%s(", sig.Return, tmp, args[0], node.Name)
 	} else {
-		sig, _ = checker.GetFunctionSignature(node.Name)
+		sig = g.info.Funcs[node.Name]
 		write(b, "%s %s = %s(", sig.Return, tmp, node.Name)
 		for i, arg := range args {
 			write(b, arg)
@@ -0,0 +1,17 @@
+// Command gen writes the C++ runtime of the builtin types to input/Builtins.cpp
+//
+//	go generate ./src/codegen
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/aniketp/meego/src/codegen"
+)
+
+func main() {
+	if err := ioutil.WriteFile("../../input/Builtins.cpp", codegen.Runtime(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
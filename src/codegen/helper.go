@@ -5,7 +5,13 @@ import (
 	"fmt"
 )
 
-var TMP_COUNT int
+// Target of a `continue` statement within a loop. Loops with a post
+// statement can't use a plain C++ continue, as it would skip the post.
+// Likewise for a `break` within a switch, which a C++ break can't leave.
+type loopLabel struct {
+	name string // empty if a plain continue suffices
+	used bool
+}
 
 func write(b *bytes.Buffer, code string, args ...interface{}) {
 	b.WriteString(fmt.Sprintf(code, args...))
@@ -17,7 +23,12 @@ func check(err error) {
 	}
 }
 
-func freshTemp() string {
-	TMP_COUNT += 1
-	return fmt.Sprintf("tmp_%d", TMP_COUNT)
+func (g *Generator) freshTemp() string {
+	g.tmpCount += 1
+	return fmt.Sprintf("tmp_%d", g.tmpCount)
+}
+
+func (g *Generator) freshLabel(kind string) string {
+	g.labelCount += 1
+	return fmt.Sprintf("%s_%d", kind, g.labelCount)
 }
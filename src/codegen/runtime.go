@@ -0,0 +1,111 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/aniketp/meego/src/checker"
+)
+
+// Native C++ form of each builtin type: how a value of the class is turned
+// into it (%s is the value), and back into the class
+var natives = map[string]struct{ from, to string }{
+	checker.BOOL_TYPE:   {"(%s.val == True)", "Bool((%s) ? True : False)"},
+	checker.STRING_TYPE: {"%s.val", "String(%s)"},
+	checker.INT_TYPE:    {"%s.valInt", "Int(%s)"},
+}
+
+// Members of each class, besides its methods
+var members = map[string]string{
+	checker.BOOL_TYPE: `	Bool(string x) {
+		val = x;
+	}
+`,
+	checker.STRING_TYPE: `	String(string x) {
+		val = x;
+	}
+`,
+	checker.INT_TYPE: `	int valInt;
+	Int(int x) {
+		val = to_string(x);
+		valInt = x;
+	}
+`,
+}
+
+const prelude = `// C++ Builtins, generated from checker.Builtins. DO NOT EDIT.
+
+#include <cstdlib>
+#include <iostream>
+#include <string>
+
+using namespace std;
+
+
+// Nothing Class
+class Nothing {
+};
+
+// Base Class
+class Base {
+public:
+	string val;
+	Nothing PRINT(void) {
+		cout << val << endl;
+		return Nothing();
+	}
+};
+
+const string True = "true";
+const string False = "false";
+
+// Integer division, which fails on a zero divisor rather than crash
+int divide(int x, int y) {
+	if (y == 0) {
+		cerr << "runtime error: division by zero" << endl;
+		exit(1);
+	}
+	return x / y;
+}
+`
+
+//go:generate go run ./gen
+
+// Runtime generates the C++ classes of the builtin types, which the
+// generated code includes as Builtins.cpp
+func Runtime() []byte {
+	var b bytes.Buffer
+	b.WriteString(prelude)
+
+	for _, kind := range checker.Builtins {
+		write(&b, "\n// %s Class\nclass %s: public Base {\npublic:\n%s",
+			kind.Name, kind.Name, members[kind.Name])
+
+		// The zero value, for variables declared without a value
+		write(&b, "\t%s() : %s {}\n", kind.Name,
+			fmt.Sprintf(natives[kind.Name].to, kind.Zero))
+
+		for _, method := range kind.Methods {
+			// Provided by the Base class
+			if method.Cpp == "" {
+				continue
+			}
+
+			params := make([]string, len(method.Params))
+			args := []interface{}{fmt.Sprintf(natives[kind.Name].from, "(*this)")}
+			for i, param := range method.Params {
+				arg := fmt.Sprintf("arg%d", i)
+				params[i] = param + " " + arg
+				args = append(args, fmt.Sprintf(natives[param].from, arg))
+			}
+
+			result := fmt.Sprintf(natives[method.Return].to, fmt.Sprintf(method.Cpp, args...))
+			write(&b, "\n\t%s %s(%s) {\n\t\treturn %s;\n\t}\n", method.Return,
+				method.Method, strings.Join(params, ", "), result)
+		}
+		b.WriteString("};\n")
+	}
+
+	return b.Bytes()
+}
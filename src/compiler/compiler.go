@@ -0,0 +1,100 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+	"github.com/aniketp/meego/src/codegen"
+	"github.com/aniketp/meego/src/lexer"
+	"github.com/aniketp/meego/src/parser"
+	"github.com/aniketp/meego/src/toolchain"
+)
+
+/*Options : how to compile a program */
+type Options struct {
+	File      string // name of the source, as reported in diagnostics
+	CheckOnly bool   // stop after type checking, without generating C++
+}
+
+/*Result : everything produced by compiling a program */
+type Result struct {
+	Program     *ast.Program
+	Info        *checker.TypeInfo
+	Code        string // generated C++, only if the program has no errors
+	Diagnostics checker.Diagnostics
+}
+
+// Parse the source, reporting lexer and parser errors as diagnostics
+func Parse(file string, src []byte) (*ast.Program, checker.Diagnostics) {
+	l := lexer.NewLexer(src)
+	p := parser.NewParser()
+
+	node, err := p.Parse(l)
+	if err != nil {
+		return nil, checker.Diagnostics{parseDiagnostic(file, err)}
+	}
+
+	program, _ := node.(*ast.Program)
+	return program, nil
+}
+
+// Parse, type check and generate C++ for the source. The result holds
+// everything that was produced, even on error. Problems in the program are
+// returned as its diagnostics, while a bug in meego is returned as an
+// internal error rather than a panic.
+func Compile(src []byte, opts *Options) (res *Result, err error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	res = &Result{}
+	defer func() {
+		if r := recover(); r != nil {
+			res.Diagnostics = append(res.Diagnostics, InternalDiagnostic(opts.File, r))
+			err = res.Diagnostics
+		}
+	}()
+
+	res.Program, res.Diagnostics = Parse(opts.File, src)
+	if res.Program == nil {
+		return res, res.Diagnostics
+	}
+
+	res.Info, res.Diagnostics = checker.CheckFile(opts.File, res.Program)
+	if res.Diagnostics.HasErrors() {
+		return res, res.Diagnostics
+	}
+
+	if !opts.CheckOnly {
+		code := codegen.GenWrapper(res.Program, res.Info)
+		res.Code = code.String()
+	}
+	return res, nil
+}
+
+// Build generated C++ into a binary at output, alongside the C++ runtime
+// of the builtin types. Warnings of the C++ compiler are returned.
+func Build(code, runtime []byte, output string, tc *toolchain.Toolchain) (string, error) {
+	dir, err := ioutil.TempDir("", "meego")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	// The generated code includes the runtime from its own directory
+	err = ioutil.WriteFile(filepath.Join(dir, "Builtins.cpp"), runtime, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	source := filepath.Join(dir, "main.cpp")
+	err = ioutil.WriteFile(source, code, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return tc.Compile(output, source)
+}
@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+	parseError "github.com/aniketp/meego/src/errors"
+	"github.com/aniketp/meego/src/token"
+)
+
+// Convert an error of the generated lexer/parser into a diagnostic
+func parseDiagnostic(file string, err error) checker.Diagnostic {
+	diag := checker.Diagnostic{File: file, Severity: checker.ERROR,
+		Code: checker.SYNTAX_ERROR, Message: err.Error()}
+
+	e, ok := err.(*parseError.Error)
+	if !ok || e.ErrorToken == nil {
+		return diag
+	}
+
+	// Syntax errors the parser recovered from are passed on by the AST
+	// constructors, and wrapped once more by the parser
+	for {
+		inner, ok := e.Err.(*parseError.Error)
+		if !ok || inner.ErrorToken == nil {
+			break
+		}
+		e = inner
+	}
+
+	tok := e.ErrorToken
+	diag.Start = ast.Pos{Line: tok.Pos.Line, Column: tok.Pos.Column}
+	diag.End = ast.Pos{Line: tok.Pos.Line, Column: tok.Pos.Column + len(tok.Lit)}
+
+	switch {
+	case e.Err != nil:
+		// Raised by one of the AST constructors
+		diag.Message = e.Err.Error()
+		return diag
+	case tok.Type == token.INVALID:
+		diag.Code = checker.LEXICAL_ERROR
+		diag.Message = fmt.Sprintf("invalid token %q", tok.Lit)
+	case tok.Type == token.EOF:
+		diag.Message = "unexpected end of file"
+	default:
+		diag.Message = fmt.Sprintf("unexpected %q", tok.Lit)
+	}
+
+	// Leave out the parser's error recovery symbol
+	var expected []string
+	for _, tok := range e.ExpectedTokens {
+		if tok != "error" {
+			expected = append(expected, tok)
+		}
+	}
+
+	if len(expected) != 0 {
+		diag.Message += ", expected one of: " + strings.Join(expected, " ")
+	}
+	return diag
+}
+
+// Diagnostic for a bug within meego itself
+func InternalDiagnostic(file string, r interface{}) checker.Diagnostic {
+	return checker.Diagnostic{File: file, Severity: checker.ERROR,
+		Code: checker.INTERNAL_ERROR, Message: fmt.Sprintf("internal compiler error: %v", r)}
+}
@@ -0,0 +1,173 @@
+package interp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+)
+
+/*Value : runtime value of one of the builtin types */
+type Value interface {
+	Type() string
+	String() string // as printed by PRINT
+}
+
+// Values mirror the classes of input/Builtins.cpp, down to the C++ int
+// backing an Int, so that both backends print the same output
+type Int int32
+type String string
+type Bool bool
+type Nothing struct{}
+
+func (i Int) Type() string     { return checker.INT_TYPE }
+func (s String) Type() string  { return checker.STRING_TYPE }
+func (b Bool) Type() string    { return checker.BOOL_TYPE }
+func (n Nothing) Type() string { return checker.NOTHING_TYPE }
+
+func (i Int) String() string     { return strconv.Itoa(int(i)) }
+func (s String) String() string  { return string(s) }
+func (n Nothing) String() string { return "" }
+func (b Bool) String() string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// Value of a literal expression
+func Literal(node ast.Expression) Value {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		// Out of range literals wrap around, like they do for a C++ int
+		val, _ := strconv.ParseInt(node.Value, 10, 64)
+		return Int(val)
+	case *ast.StringLiteral:
+		return String(strings.Trim(node.Value, `"`))
+	case *ast.Boolean:
+		return Bool(node.Value)
+	}
+
+	panic("not a literal " + node.TokenLiteral())
+}
+
+// Implementation of a method of the TypeTable. Unary methods get no arg.
+type method func(recv, arg Value) Value
+
+/*Methods : builtin methods of every type, from the folds of checker.Builtins */
+var Methods = methods(checker.Builtins)
+
+func methods(types []checker.BuiltinType) map[string]map[string]method {
+	table := map[string]map[string]method{}
+	for _, kind := range types {
+		table[kind.Name] = map[string]method{}
+		for _, builtin := range kind.Methods {
+			// PRINT is a builtin function rather than a method
+			if builtin.Fold != nil {
+				table[kind.Name][builtin.Method] = run(builtin.Fold)
+			}
+		}
+	}
+	return table
+}
+
+// Run a fold on values, where its errors, e.g. a division by zero, are
+// runtime errors
+func run(fold checker.Fold) method {
+	return func(x, y Value) Value {
+		res, err := fold(native(x), native(y))
+		if err != nil {
+			panic(RuntimeError(err.Error()))
+		}
+		return value(res)
+	}
+}
+
+// Go value of a Value, as folds take them
+func native(v Value) interface{} {
+	switch v := v.(type) {
+	case Int:
+		return int32(v)
+	case String:
+		return string(v)
+	case Bool:
+		return bool(v)
+	}
+	return nil
+}
+
+func value(v interface{}) Value {
+	switch v := v.(type) {
+	case int32:
+		return Int(v)
+	case string:
+		return String(v)
+	case bool:
+		return Bool(v)
+	}
+	panic("not a value of a builtin type")
+}
+
+/*Zero : value of a variable declared without one, by type */
+var Zero = map[string]Value{
+	checker.INT_TYPE:    Int(0),
+	checker.STRING_TYPE: String(""),
+	checker.BOOL_TYPE:   Bool(false),
+}
+
+/*RuntimeError : error of a well-typed program, such as a division by zero */
+type RuntimeError string
+
+func (e RuntimeError) Error() string { return "runtime error: " + string(e) }
+
+/*Environment : values of a single lexical scope */
+type Environment struct {
+	Vals  map[string]Value
+	Outer *Environment
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{Vals: map[string]Value{}}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	return &Environment{Vals: map[string]Value{}, Outer: outer}
+}
+
+// Declare name in the current scope
+func (e *Environment) Set(name string, val Value) {
+	e.Vals[name] = val
+}
+
+// Assign to name in the innermost scope declaring it
+func (e *Environment) Assign(name string, val Value) {
+	for scope := e; scope != nil; scope = scope.Outer {
+		if _, ok := scope.Vals[name]; ok {
+			scope.Vals[name] = val
+			return
+		}
+	}
+
+	panic("assignment to undeclared " + name)
+}
+
+func (e *Environment) Get(name string) Value {
+	for scope := e; scope != nil; scope = scope.Outer {
+		if val, ok := scope.Vals[name]; ok {
+			return val
+		}
+	}
+
+	panic("use of undeclared " + name)
+}
+
+// How a statement passes control on
+type flow int
+
+const (
+	next       flow = iota // carry on with the following statement
+	returning              // leave the function
+	breaking               // leave the innermost loop
+	continuing             // go on with the next iteration
+)
@@ -0,0 +1,326 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+)
+
+// Deepest call stack before giving up, rather than running out of memory
+const MaxFrames = 1 << 16
+
+/*Interpreter : evaluates a checked program without going through C++ */
+type Interpreter struct {
+	info   *checker.TypeInfo // of every program evaluated
+	out    *bufio.Writer
+	env    *Environment
+	funcs  map[string]*ast.FunctionStatement
+	frames int
+}
+
+func New(out io.Writer) *Interpreter {
+	return &Interpreter{info: checker.NewTypeInfo(), out: bufio.NewWriter(out),
+		env: NewEnvironment(), funcs: map[string]*ast.FunctionStatement{}}
+}
+
+// Run a program that passed the checker, writing what it prints to out
+func Run(program *ast.Program, info *checker.TypeInfo, out io.Writer) (err error) {
+	i := New(out)
+	i.info = info
+	defer i.recover(&err)
+
+	i.eval(program)
+	return i.out.Flush()
+}
+
+// Execute a checked program within the outermost scope of the interpreter,
+// keeping its functions, declarations and types for the programs that
+// follow, as the lines entered in a REPL do
+func (i *Interpreter) Exec(program *ast.Program, info *checker.TypeInfo) (err error) {
+	defer i.recover(&err)
+	i.info.Merge(info)
+
+	i.declare(program.Functions)
+	i.evalStatements(program.Statements)
+	return i.out.Flush()
+}
+
+// Evaluate a checked expression within the outermost scope
+func (i *Interpreter) Eval(expr ast.Expression, info *checker.TypeInfo) (val Value, err error) {
+	defer i.recover(&err)
+	i.info.Merge(info)
+
+	val = i.evalExpression(expr)
+	return val, i.out.Flush()
+}
+
+// Value of a name declared by an executed program
+func (i *Interpreter) Get(name string) Value {
+	return i.env.Get(name)
+}
+
+// Turn a panic during evaluation into an error, leaving the interpreter
+// usable
+func (i *Interpreter) recover(err *error) {
+	if r := recover(); r != nil {
+		i.env = rootOf(i.env)
+		i.out.Flush()
+		if e, ok := r.(RuntimeError); ok {
+			*err = e
+		} else {
+			*err = fmt.Errorf("runtime error: %v", r)
+		}
+	}
+}
+
+// Evaluate a statement, telling how control flows on from it along with the
+// returned value, if any
+func (i *Interpreter) eval(node ast.Node) (flow, Value) {
+	switch node := node.(type) {
+	case *ast.Program:
+		return i.evalProgram(node)
+	case *ast.BlockStatement:
+		return i.evalBlockStatement(node)
+	case *ast.ReturnStatement:
+		return returning, i.evalExpression(node.ReturnValue)
+	case *ast.IfStatement:
+		return i.evalIfStatement(node)
+	case *ast.ForStatement:
+		return i.evalForStatement(node)
+	case *ast.SwitchStatement:
+		return i.evalSwitchStatement(node)
+	case *ast.BreakStatement:
+		return breaking, nil
+	case *ast.ContinueStatement:
+		return continuing, nil
+	case *ast.ExpressionStatement:
+		i.evalExpression(node.Expression)
+	case *ast.AssignStatement:
+		i.env.Assign(node.Left.Value, i.evalExpression(node.Right))
+	case *ast.InitStatement:
+		if node.Expr == nil {
+			i.env.Set(node.Location, Zero[node.Type])
+		} else {
+			i.env.Set(node.Location, i.evalExpression(node.Expr))
+		}
+	}
+
+	return next, nil
+}
+
+func (i *Interpreter) evalExpression(node ast.Expression) Value {
+	switch node := node.(type) {
+	case *ast.InfixExpression:
+		return i.evalInfixExpression(node)
+	case *ast.PrefixExpression:
+		return i.evalPrefixExpression(node)
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return Literal(node)
+	case *ast.Identifier:
+		return i.env.Get(node.Value)
+	case *ast.FunctionCall:
+		return i.evalFunctionCall(node)
+	}
+
+	panic("cannot evaluate " + node.TokenLiteral())
+}
+
+// Target program
+func (i *Interpreter) evalProgram(p *ast.Program) (flow, Value) {
+	i.declare(p.Functions)
+
+	// Top-level statements get a scope of their own, hidden from functions
+	root := i.env
+	i.env = NewEnclosedEnvironment(root)
+	defer func() { i.env = root }()
+
+	return i.evalStatements(p.Statements)
+}
+
+func (i *Interpreter) declare(functions []ast.Statement) {
+	for _, function := range functions {
+		if fn, ok := function.(*ast.FunctionStatement); ok {
+			i.funcs[fn.Name] = fn
+		}
+	}
+}
+
+// Statements
+func (i *Interpreter) evalBlockStatement(node *ast.BlockStatement) (flow, Value) {
+	outer := i.env
+	i.env = NewEnclosedEnvironment(outer)
+	defer func() { i.env = outer }()
+
+	return i.evalStatements(node.Statements)
+}
+
+// Evaluate statements within the current scope, up to the first one that
+// transfers control elsewhere
+func (i *Interpreter) evalStatements(statements []ast.Statement) (flow, Value) {
+	for _, statement := range statements {
+		if ctl, val := i.eval(statement); ctl != next {
+			return ctl, val
+		}
+	}
+
+	return next, nil
+}
+
+func (i *Interpreter) evalIfStatement(node *ast.IfStatement) (flow, Value) {
+	if i.evalExpression(node.Condition).(Bool) {
+		return i.eval(node.Block)
+	}
+
+	if node.Alternative != nil {
+		return i.eval(node.Alternative)
+	}
+	return next, nil
+}
+
+func (i *Interpreter) evalSwitchStatement(node *ast.SwitchStatement) (flow, Value) {
+	value := i.evalExpression(node.Value)
+	equal := Methods[i.info.TypeOf(node.Value)][checker.EQUAL]
+
+	// The default is taken when no case matches, wherever it's written
+	var def *ast.CaseClause
+	for _, clause := range node.Cases {
+		if clause.Values == nil {
+			def = clause
+		}
+		for _, val := range clause.Values {
+			if equal(value, i.evalExpression(val)).(Bool) {
+				return i.evalCase(clause)
+			}
+		}
+	}
+
+	if def != nil {
+		return i.evalCase(def)
+	}
+	return next, nil
+}
+
+// Evaluate the body of a case, where break ends the switch
+func (i *Interpreter) evalCase(clause *ast.CaseClause) (flow, Value) {
+	ctl, val := i.eval(clause.Body)
+	if ctl == breaking {
+		return next, nil
+	}
+	return ctl, val
+}
+
+func (i *Interpreter) evalForStatement(node *ast.ForStatement) (flow, Value) {
+	// The init statement is scoped to the loop
+	outer := i.env
+	i.env = NewEnclosedEnvironment(outer)
+	defer func() { i.env = outer }()
+
+	if node.Init != nil {
+		i.eval(node.Init)
+	}
+
+	for i.evalExpression(node.Condition).(Bool) {
+		ctl, val := i.eval(node.BlockStatement)
+		if ctl == breaking {
+			break
+		} else if ctl == returning {
+			return ctl, val
+		}
+
+		if node.Post != nil {
+			i.eval(node.Post)
+		}
+	}
+
+	return next, nil
+}
+
+// Expressions
+func (i *Interpreter) evalFunctionCall(node *ast.FunctionCall) Value {
+	args := make([]Value, len(node.Args))
+	for n, arg := range node.Args {
+		args[n] = i.evalExpression(arg)
+	}
+
+	// print() function call
+	if checker.IsBuiltin(node.Name) {
+		i.out.WriteString(args[0].String() + "\n")
+		return Nothing{}
+	}
+
+	if i.frames == MaxFrames {
+		panic(RuntimeError("stack overflow"))
+	}
+	i.frames++
+	defer func() { i.frames-- }()
+
+	// Functions only see their parameters, within a scope of the root
+	fn := i.funcs[node.Name]
+	outer := i.env
+	i.env = NewEnclosedEnvironment(rootOf(outer))
+	defer func() { i.env = outer }()
+
+	for n, param := range fn.Parameters {
+		i.env.Set(param.Arg, args[n])
+	}
+
+	_, val := i.evalStatements(fn.Body.Statements)
+	if val == nil {
+		return Nothing{}
+	}
+	return val
+}
+
+// Evaluate the provided expression in infix form
+func (i *Interpreter) evalInfixExpression(node *ast.InfixExpression) Value {
+	left := i.evalExpression(node.Left)
+
+	// The right operand of `and` and `or` is only evaluated if needed
+	switch node.Operator {
+	case "and":
+		if !left.(Bool) {
+			return left
+		}
+		return i.evalExpression(node.Right)
+	case "or":
+		if left.(Bool) {
+			return left
+		}
+		return i.evalExpression(node.Right)
+	}
+
+	right := i.evalExpression(node.Right)
+
+	// Methods are looked up by the static type of the receiver, as in C++
+	kind := i.info.TypeOf(node.Left)
+	method, ok := Methods[kind][checker.Operators[node.Operator]]
+	if !ok {
+		panic("no method " + checker.Operators[node.Operator] + " for type " + kind)
+	}
+
+	return method(left, right)
+}
+
+// Evaluate the provided expression in prefix form
+func (i *Interpreter) evalPrefixExpression(node *ast.PrefixExpression) Value {
+	right := i.evalExpression(node.Right)
+
+	kind := i.info.TypeOf(node.Right)
+	method, ok := Methods[kind][checker.Prefixes[node.Operator]]
+	if !ok {
+		panic("no method " + checker.Prefixes[node.Operator] + " for type " + kind)
+	}
+
+	return method(right, nil)
+}
+
+// Outermost scope of env
+func rootOf(env *Environment) *Environment {
+	for env.Outer != nil {
+		env = env.Outer
+	}
+	return env
+}
@@ -0,0 +1,239 @@
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+)
+
+const (
+	PROMPT       = ">>> "
+	CONTINUATION = "... " // prompt while braces or parentheses are open
+)
+
+const help = `Enter statements, functions and expressions (the final ; is optional).
+  :type expr   show the type of an expression
+  :ast input   show the syntax tree of the input
+  :help        show this message
+  :quit        leave the REPL (or Ctrl-D)
+`
+
+/*Session : declarations made so far in a REPL */
+type Session struct {
+	env    *checker.Environment // types, outliving every entry
+	interp *interp.Interpreter  // values, in step with env
+	out    io.Writer
+}
+
+func NewSession(out io.Writer) *Session {
+	return &Session{env: checker.NewEnvironment(), interp: interp.New(out), out: out}
+}
+
+// Start reads entries from in until it ends, writing the results to out
+func Start(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	s := NewSession(out)
+
+	var lines []string
+	for {
+		if len(lines) == 0 {
+			fmt.Fprint(out, PROMPT)
+		} else {
+			fmt.Fprint(out, CONTINUATION)
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		// Keep reading until the entry is balanced, e.g. a function body
+		lines = append(lines, scanner.Text())
+		entry := strings.Join(lines, "\n")
+		if !balanced(entry) {
+			continue
+		}
+		lines = nil
+
+		if strings.TrimSpace(entry) == ":quit" {
+			return
+		}
+		s.Eval(entry)
+	}
+}
+
+// Eval handles a single entry, reporting any error without ending the session
+func (s *Session) Eval(entry string) {
+	entry = strings.TrimSpace(entry)
+	switch {
+	case entry == "":
+	case entry == ":help":
+		fmt.Fprint(s.out, help)
+	case strings.HasPrefix(entry, ":type "):
+		s.typeOf(strings.TrimPrefix(entry, ":type "))
+	case strings.HasPrefix(entry, ":ast "):
+		s.syntaxTree(strings.TrimPrefix(entry, ":ast "))
+	case strings.HasPrefix(entry, ":"):
+		fmt.Fprintf(s.out, "unknown command %s, try :help\n", strings.Fields(entry)[0])
+	default:
+		s.exec(entry)
+	}
+}
+
+func (s *Session) exec(entry string) {
+	program, ok := s.parse(entry)
+	if !ok {
+		return
+	}
+
+	// A lone expression shows its value
+	if expr, ok := expression(program); ok {
+		info, ok := s.check(expr)
+		if !ok {
+			return
+		}
+
+		kind := info.TypeOf(expr)
+		val, err := s.interp.Eval(expr, info)
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+		} else if kind != checker.NOTHING_TYPE {
+			fmt.Fprintf(s.out, "%s : %s\n", inspect(val), kind)
+		}
+		return
+	}
+
+	saved := s.env.Save()
+	info, diags := checker.CheckIn(s.env, "", program)
+	s.report(diags)
+	if diags.HasErrors() {
+		return
+	}
+
+	// Nor does an entry that fails at runtime declare anything
+	if err := s.interp.Exec(program, info); err != nil {
+		s.env.Restore(saved)
+		fmt.Fprintln(s.out, err)
+		return
+	}
+
+	// Show what was declared
+	for _, function := range program.Functions {
+		if fn, ok := function.(*ast.FunctionStatement); ok {
+			sig := info.Funcs[fn.Name]
+			fmt.Fprintf(s.out, "%s : func(%s) %s\n", fn.Name,
+				strings.Join(sig.Params, ", "), sig.Return)
+		}
+	}
+
+	for _, statement := range program.Statements {
+		if init, ok := statement.(*ast.InitStatement); ok {
+			kind, _ := s.env.Get(init.Location)
+			fmt.Fprintf(s.out, "%s : %s = %s\n", init.Location, kind,
+				inspect(s.interp.Get(init.Location)))
+		}
+	}
+}
+
+func (s *Session) typeOf(entry string) {
+	program, ok := s.parse(entry)
+	if !ok {
+		return
+	}
+
+	expr, ok := expression(program)
+	if !ok {
+		fmt.Fprintln(s.out, ":type expects an expression")
+		return
+	}
+
+	if info, ok := s.check(expr); ok {
+		fmt.Fprintln(s.out, info.TypeOf(expr))
+	}
+}
+
+func (s *Session) syntaxTree(entry string) {
+	program, ok := s.parse(entry)
+	if !ok {
+		return
+	}
+
+	var node interface{} = program
+	if expr, ok := expression(program); ok {
+		node = expr
+	}
+
+	js, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	fmt.Fprintln(s.out, string(js))
+}
+
+// Parse an entry, for which the final semicolon is optional
+func (s *Session) parse(entry string) (*ast.Program, bool) {
+	if !strings.HasSuffix(entry, ";") && !strings.HasSuffix(entry, "}") {
+		entry += ";"
+	}
+
+	program, diags := compiler.Parse("", []byte(entry))
+	s.report(diags)
+	return program, program != nil
+}
+
+// Type check an expression, without declaring anything
+func (s *Session) check(expr ast.Expression) (*checker.TypeInfo, bool) {
+	info, diags := checker.CheckExpr(s.env, "", expr)
+	s.report(diags)
+	return info, !diags.HasErrors()
+}
+
+func (s *Session) report(diags checker.Diagnostics) {
+	for _, diag := range diags {
+		fmt.Fprintln(s.out, diag)
+	}
+}
+
+// The expression of a program made of a single expression statement
+func expression(program *ast.Program) (ast.Expression, bool) {
+	if len(program.Functions) != 0 || len(program.Statements) != 1 {
+		return nil, false
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	return stmt.Expression, true
+}
+
+// Show a value the way it's written in the source
+func inspect(val interp.Value) string {
+	if str, ok := val.(interp.String); ok {
+		return strconv.Quote(string(str))
+	}
+	return val.String()
+}
+
+// Check if every brace and parenthesis of the entry is closed
+func balanced(entry string) bool {
+	depth := 0
+	for _, c := range entry {
+		switch c {
+		case '{', '(':
+			depth++
+		case '}', ')':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,105 @@
+package toolchain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Families of supported C++ compilers
+const (
+	GCC   = "gcc"
+	CLANG = "clang"
+)
+
+// Defaults, unless overridden by the environment or the driver
+const (
+	DEFAULT_CXX = "g++"
+	DEFAULT_STD = "c++11"
+)
+
+/*Toolchain : a C++ compiler and the flags to invoke it with */
+type Toolchain struct {
+	CXX   string   // compiler command, e.g. g++ or clang++
+	Std   string   // C++ standard, e.g. c++11
+	Opt   string   // optimization level, e.g. 0, 2 or s (none if empty)
+	Flags []string // extra flags
+}
+
+/*Error : a compilation that failed */
+type Error struct {
+	CXX    string
+	Status int    // exit status of the compiler, -1 if it couldn't run
+	Output string // errors written by the compiler
+}
+
+func (e *Error) Error() string {
+	if e.Status < 0 {
+		return fmt.Sprintf("%s: %s", e.CXX, e.Output)
+	}
+
+	return fmt.Sprintf("%s exited with status %d:\n%s", e.CXX, e.Status, e.Output)
+}
+
+// Toolchain honoring the CXX and CXXFLAGS environment variables
+func Default() *Toolchain {
+	tc := &Toolchain{CXX: DEFAULT_CXX, Std: DEFAULT_STD}
+	if cxx := os.Getenv("CXX"); cxx != "" {
+		tc.CXX = cxx
+	}
+
+	tc.Flags = strings.Fields(os.Getenv("CXXFLAGS"))
+	return tc
+}
+
+// Family of the compiler, judging by its name
+func (tc *Toolchain) Family() string {
+	if strings.Contains(filepath.Base(tc.CXX), "clang") {
+		return CLANG
+	}
+
+	return GCC
+}
+
+// Arguments compiling the sources into the binary at output
+func (tc *Toolchain) Args(output string, sources ...string) []string {
+	args := []string{"-std=" + tc.Std}
+	if tc.Opt != "" {
+		args = append(args, "-O"+tc.Opt)
+	}
+
+	// Plain text output, as it's passed on to the user
+	switch tc.Family() {
+	case CLANG:
+		args = append(args, "-fno-color-diagnostics")
+	case GCC:
+		args = append(args, "-fdiagnostics-color=never")
+	}
+
+	args = append(args, tc.Flags...)
+	args = append(args, "-o", output)
+	return append(args, sources...)
+}
+
+// Compile the sources into the binary at output. Success is decided by the
+// exit status of the compiler, and anything it writes on success is
+// returned as warnings.
+func (tc *Toolchain) Compile(output string, sources ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(tc.CXX, tc.Args(output, sources...)...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return "", &Error{tc.CXX, exit.ExitCode(), out.String()}
+	} else if err != nil {
+		// The compiler couldn't be started at all
+		return "", &Error{tc.CXX, -1, err.Error()}
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/aniketp/meego/src/interp"
+)
+
+// Opcodes of the stack machine. Every operand is a big-endian uint16.
+type Opcode byte
+
+const (
+	OpConstant    Opcode = iota // push a constant
+	OpGetLocal                  // push a local of the current frame
+	OpSetLocal                  // pop into a local of the current frame
+	OpPop                       // discard the top of the stack
+	OpMethod                    // pop the argument and receiver, push the method's result
+	OpPrint                     // pop a value and print it
+	OpCall                      // call a function on the arguments at the top of the stack
+	OpReturn                    // pop the result and return it to the caller
+	OpJump                      // jump to an offset of the function
+	OpJumpIfFalse               // pop a Bool, jumping if it's false
+	OpUnary                     // pop the receiver, push the unary method's result
+)
+
+/*Definition : how an opcode is disassembled */
+type Definition struct {
+	Name     string
+	Operands int // number of uint16 operands
+}
+
+var definitions = map[Opcode]Definition{
+	OpConstant:    {"CONSTANT", 1},
+	OpGetLocal:    {"GET_LOCAL", 1},
+	OpSetLocal:    {"SET_LOCAL", 1},
+	OpPop:         {"POP", 0},
+	OpMethod:      {"METHOD", 1},
+	OpPrint:       {"PRINT", 0},
+	OpCall:        {"CALL", 1},
+	OpReturn:      {"RETURN", 0},
+	OpJump:        {"JUMP", 1},
+	OpJumpIfFalse: {"JUMP_IF_FALSE", 1},
+	OpUnary:       {"UNARY", 1},
+}
+
+func Lookup(op Opcode) (Definition, bool) {
+	def, ok := definitions[op]
+	return def, ok
+}
+
+/*Function : bytecode of a function, or of the top-level statements */
+type Function struct {
+	Name   string
+	Params int // the first locals hold the arguments
+	Locals int // number of local slots, parameters included
+	Code   []byte
+}
+
+/*Program : compiled bytecode of a whole program */
+type Program struct {
+	Constants []interp.Value
+	Methods   []string    // names of the TypeTable methods used by OpMethod
+	Functions []*Function // called by their index
+	Main      *Function   // top-level statements
+}
+
+// Every function of the program, main last
+func (p *Program) functions() []*Function {
+	return append(p.Functions[:len(p.Functions):len(p.Functions)], p.Main)
+}
+
+// Read the operand of the instruction at offset
+func operand(code []byte, offset int) int {
+	return int(binary.BigEndian.Uint16(code[offset+1:]))
+}
+
+// Disassemble writes a human readable listing of the program
+func (p *Program) Disassemble(w io.Writer) {
+	for _, fn := range p.functions() {
+		fmt.Fprintf(w, "== %s (params %d, locals %d) ==\n", fn.Name, fn.Params, fn.Locals)
+
+		for offset := 0; offset < len(fn.Code); {
+			op := Opcode(fn.Code[offset])
+			def, ok := Lookup(op)
+			if !ok {
+				fmt.Fprintf(w, "%04d unknown opcode %d\n", offset, op)
+				offset++
+				continue
+			}
+
+			fmt.Fprintf(w, "%04d %s", offset, def.Name)
+			if def.Operands != 0 {
+				arg := operand(fn.Code, offset)
+				fmt.Fprintf(w, " %d", arg)
+				switch op {
+				case OpConstant:
+					fmt.Fprintf(w, " (%s %q)", p.Constants[arg].Type(), p.Constants[arg])
+				case OpMethod, OpUnary:
+					fmt.Fprintf(w, " (%s)", p.Methods[arg])
+				case OpCall:
+					fmt.Fprintf(w, " (%s)", p.Functions[arg].Name)
+				}
+			}
+
+			fmt.Fprintln(w)
+			offset += 1 + 2*def.Operands
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,370 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/aniketp/meego/src/ast"
+	"github.com/aniketp/meego/src/checker"
+	"github.com/aniketp/meego/src/interp"
+)
+
+/*Compiler : translates a checked program to bytecode */
+type Compiler struct {
+	program   *Program
+	constants map[interp.Value]int
+	methods   map[string]int
+	funcs     map[string]int
+
+	fn     *Function // function being compiled
+	scope  *scope
+	loops  []*loop
+	errors []error
+}
+
+// Names declared in a lexical scope, mapped to their local slots
+type scope struct {
+	slots map[string]int
+	outer *scope
+}
+
+// Jumps of an enclosing loop (or switch) waiting for their target
+type loop struct {
+	breaks    []int
+	continues []int
+	isSwitch  bool // takes breaks, but continues go to the enclosing loop
+}
+
+// Compile a program that passed the checker to bytecode
+func Compile(p *ast.Program) (*Program, error) {
+	c := &Compiler{program: &Program{}, constants: map[interp.Value]int{},
+		methods: map[string]int{}, funcs: map[string]int{}}
+
+	// Number every function before compiling any call
+	for _, function := range p.Functions {
+		if fn, ok := function.(*ast.FunctionStatement); ok {
+			c.funcs[fn.Name] = len(c.program.Functions)
+			c.program.Functions = append(c.program.Functions,
+				&Function{Name: fn.Name, Params: len(fn.Parameters)})
+		}
+	}
+
+	for _, function := range p.Functions {
+		if fn, ok := function.(*ast.FunctionStatement); ok {
+			c.compileFunction(fn)
+		}
+	}
+
+	c.program.Main = &Function{Name: "main"}
+	c.enter(c.program.Main)
+	c.compileStatements(p.Statements)
+
+	if len(c.errors) != 0 {
+		return nil, c.errors[0]
+	}
+	return c.program, nil
+}
+
+// Start compiling the body of fn, in a scope of its own
+func (c *Compiler) enter(fn *Function) {
+	c.fn = fn
+	c.scope = &scope{slots: map[string]int{}}
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionStatement) {
+	c.enter(c.program.Functions[c.funcs[node.Name]])
+	for _, param := range node.Parameters {
+		c.declare(param.Arg)
+	}
+
+	c.compileStatements(node.Body.Statements)
+
+	// Falling off the end returns Nothing
+	c.emit(OpConstant, c.constant(interp.Nothing{}))
+	c.emit(OpReturn)
+}
+
+func (c *Compiler) compileStatements(statements []ast.Statement) {
+	for _, statement := range statements {
+		c.compile(statement)
+	}
+}
+
+func (c *Compiler) compile(node ast.Node) {
+	switch node := node.(type) {
+	// Statements
+	case *ast.BlockStatement:
+		c.scope = &scope{slots: map[string]int{}, outer: c.scope}
+		c.compileStatements(node.Statements)
+		c.scope = c.scope.outer
+	case *ast.ReturnStatement:
+		c.compile(node.ReturnValue)
+		c.emit(OpReturn)
+	case *ast.IfStatement:
+		c.compileIfStatement(node)
+	case *ast.ForStatement:
+		c.compileForStatement(node)
+	case *ast.BreakStatement:
+		l := c.loops[len(c.loops)-1]
+		l.breaks = append(l.breaks, c.emit(OpJump, 0))
+	case *ast.ContinueStatement:
+		l := c.innermostLoop()
+		l.continues = append(l.continues, c.emit(OpJump, 0))
+	case *ast.SwitchStatement:
+		c.compileSwitchStatement(node)
+	case *ast.ExpressionStatement:
+		c.compile(node.Expression)
+		c.emit(OpPop)
+	case *ast.AssignStatement:
+		c.compile(node.Right)
+		c.emit(OpSetLocal, c.resolve(node.Left.Value))
+	case *ast.InitStatement:
+		if node.Expr == nil {
+			c.emit(OpConstant, c.constant(interp.Zero[node.Type]))
+		} else {
+			c.compile(node.Expr)
+		}
+		c.emit(OpSetLocal, c.declare(node.Location))
+
+	// Expressions
+	case *ast.InfixExpression:
+		if node.Operator == "and" || node.Operator == "or" {
+			c.compileLogicalExpression(node)
+			return
+		}
+		c.compile(node.Left)
+		c.compile(node.Right)
+		c.emit(OpMethod, c.method(checker.Operators[node.Operator]))
+	case *ast.PrefixExpression:
+		c.compile(node.Right)
+		c.emit(OpUnary, c.method(checker.Prefixes[node.Operator]))
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		c.emit(OpConstant, c.constant(interp.Literal(node.(ast.Expression))))
+	case *ast.Identifier:
+		c.emit(OpGetLocal, c.resolve(node.Value))
+	case *ast.FunctionCall:
+		c.compileFunctionCall(node)
+	default:
+		c.errorf("cannot compile %T", node)
+	}
+}
+
+func (c *Compiler) compileIfStatement(node *ast.IfStatement) {
+	c.compile(node.Condition)
+	jumpElse := c.emit(OpJumpIfFalse, 0)
+	c.compile(node.Block)
+
+	if node.Alternative == nil {
+		c.patch(jumpElse, len(c.fn.Code))
+		return
+	}
+
+	jumpEnd := c.emit(OpJump, 0)
+	c.patch(jumpElse, len(c.fn.Code))
+	c.compile(node.Alternative)
+	c.patch(jumpEnd, len(c.fn.Code))
+}
+
+// `and` and `or` jump over their right operand when the left one decides
+// the result
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) {
+	c.compile(node.Left)
+	jumpShort := c.emit(OpJumpIfFalse, 0)
+
+	if node.Operator == "and" {
+		c.compile(node.Right)
+		jumpEnd := c.emit(OpJump, 0)
+		c.patch(jumpShort, len(c.fn.Code))
+		c.emit(OpConstant, c.constant(interp.Bool(false)))
+		c.patch(jumpEnd, len(c.fn.Code))
+		return
+	}
+
+	// JUMP_IF_FALSE popped the left operand, which was true
+	c.emit(OpConstant, c.constant(interp.Bool(true)))
+	jumpEnd := c.emit(OpJump, 0)
+	c.patch(jumpShort, len(c.fn.Code))
+	c.compile(node.Right)
+	c.patch(jumpEnd, len(c.fn.Code))
+}
+
+func (c *Compiler) compileSwitchStatement(node *ast.SwitchStatement) {
+	// The value is computed once, into a local of its own
+	c.compile(node.Value)
+	value := c.fn.Locals
+	c.fn.Locals++
+	c.emit(OpSetLocal, value)
+
+	l := &loop{isSwitch: true}
+	c.loops = append(c.loops, l)
+
+	// The default is taken when no case matches, wherever it's written
+	var def *ast.CaseClause
+	for _, clause := range node.Cases {
+		if clause.Values == nil {
+			def = clause
+			continue
+		}
+
+		// Compare with each value of the case in order, up to a match
+		var matches []int
+		for _, val := range clause.Values {
+			c.emit(OpGetLocal, value)
+			c.compile(val)
+			c.emit(OpMethod, c.method(checker.EQUAL))
+			differ := c.emit(OpJumpIfFalse, 0)
+			matches = append(matches, c.emit(OpJump, 0))
+			c.patch(differ, len(c.fn.Code))
+		}
+		nextCase := c.emit(OpJump, 0)
+
+		for _, jump := range matches {
+			c.patch(jump, len(c.fn.Code))
+		}
+		c.compile(clause.Body)
+		l.breaks = append(l.breaks, c.emit(OpJump, 0))
+		c.patch(nextCase, len(c.fn.Code))
+	}
+
+	if def != nil {
+		c.compile(def.Body)
+	}
+
+	c.loops = c.loops[:len(c.loops)-1]
+	for _, jump := range l.breaks {
+		c.patch(jump, len(c.fn.Code))
+	}
+}
+
+// Innermost enclosing loop, skipping the switches within it
+func (c *Compiler) innermostLoop() *loop {
+	for n := len(c.loops) - 1; ; n-- {
+		if !c.loops[n].isSwitch {
+			return c.loops[n]
+		}
+	}
+}
+
+func (c *Compiler) compileForStatement(node *ast.ForStatement) {
+	// The init statement is scoped to the loop
+	c.scope = &scope{slots: map[string]int{}, outer: c.scope}
+	defer func() { c.scope = c.scope.outer }()
+
+	if node.Init != nil {
+		c.compile(node.Init)
+	}
+
+	start := len(c.fn.Code)
+	c.compile(node.Condition)
+	exit := c.emit(OpJumpIfFalse, 0)
+
+	l := &loop{}
+	c.loops = append(c.loops, l)
+	c.compile(node.BlockStatement)
+	c.loops = c.loops[:len(c.loops)-1]
+
+	// A continue goes on with the post statement
+	for _, jump := range l.continues {
+		c.patch(jump, len(c.fn.Code))
+	}
+
+	if node.Post != nil {
+		c.compile(node.Post)
+	}
+	c.emit(OpJump, start)
+
+	c.patch(exit, len(c.fn.Code))
+	for _, jump := range l.breaks {
+		c.patch(jump, len(c.fn.Code))
+	}
+}
+
+func (c *Compiler) compileFunctionCall(node *ast.FunctionCall) {
+	for _, arg := range node.Args {
+		c.compile(arg)
+	}
+
+	// print() function call
+	if checker.IsBuiltin(node.Name) {
+		c.emit(OpPrint)
+		c.emit(OpConstant, c.constant(interp.Nothing{}))
+		return
+	}
+
+	index, ok := c.funcs[node.Name]
+	if !ok {
+		c.errorf("undefined function %s", node.Name)
+		return
+	}
+	c.emit(OpCall, index)
+}
+
+// Append an instruction to the current function, returning its offset
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	offset := len(c.fn.Code)
+	c.fn.Code = append(c.fn.Code, byte(op))
+	for _, arg := range operands {
+		if arg > math.MaxUint16 {
+			c.errorf("operand %d of %s out of range", arg, definitions[op].Name)
+		}
+		c.fn.Code = binary.BigEndian.AppendUint16(c.fn.Code, uint16(arg))
+	}
+	return offset
+}
+
+// Point the jump at offset to target
+func (c *Compiler) patch(offset, target int) {
+	if target > math.MaxUint16 {
+		c.errorf("function %s is too long", c.fn.Name)
+	}
+	binary.BigEndian.PutUint16(c.fn.Code[offset+1:], uint16(target))
+}
+
+// Index of a constant, shared by all its uses
+func (c *Compiler) constant(val interp.Value) int {
+	if index, ok := c.constants[val]; ok {
+		return index
+	}
+
+	index := len(c.program.Constants)
+	c.program.Constants = append(c.program.Constants, val)
+	c.constants[val] = index
+	return index
+}
+
+func (c *Compiler) method(name string) int {
+	if index, ok := c.methods[name]; ok {
+		return index
+	}
+
+	index := len(c.program.Methods)
+	c.program.Methods = append(c.program.Methods, name)
+	c.methods[name] = index
+	return index
+}
+
+// Allocate a local slot for name in the current scope. Slots aren't reused
+// once their scope ends, so that a frame never needs to grow.
+func (c *Compiler) declare(name string) int {
+	slot := c.fn.Locals
+	c.fn.Locals++
+	c.scope.slots[name] = slot
+	return slot
+}
+
+// Slot of the innermost declaration of name
+func (c *Compiler) resolve(name string) int {
+	for s := c.scope; s != nil; s = s.outer {
+		if slot, ok := s.slots[name]; ok {
+			return slot
+		}
+	}
+
+	c.errorf("undefined: %s", name)
+	return 0
+}
+
+func (c *Compiler) errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Errorf(format, args...))
+}
@@ -0,0 +1,247 @@
+package vm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aniketp/meego/src/interp"
+)
+
+// Serialized bytecode starts with the magic number, then the format version
+const (
+	Magic   = "MEGO"
+	Version = 1
+)
+
+// Tags of the serialized constants
+const (
+	tagInt byte = iota
+	tagString
+	tagBool
+	tagNothing
+)
+
+// Check if data holds serialized bytecode
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Encode writes the program in the serialized bytecode format:
+//
+//	magic, version
+//	constants: uint16 count, then a tag and the value of each
+//	methods:   uint16 count, then each name
+//	functions: uint16 count, then each function, followed by main
+//
+// Strings are prefixed by their uint32 length, and every function by its
+// name, uint16 parameter and local counts, and its code as a string.
+func (p *Program) Encode(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.bytes([]byte(Magic))
+	e.byte(Version)
+
+	e.uint16(len(p.Constants))
+	for _, val := range p.Constants {
+		switch val := val.(type) {
+		case interp.Int:
+			e.byte(tagInt)
+			e.put(int32(val))
+		case interp.String:
+			e.byte(tagString)
+			e.string(string(val))
+		case interp.Bool:
+			e.byte(tagBool)
+			e.put(bool(val))
+		case interp.Nothing:
+			e.byte(tagNothing)
+		default:
+			return fmt.Errorf("cannot encode constant of type %T", val)
+		}
+	}
+
+	e.uint16(len(p.Methods))
+	for _, name := range p.Methods {
+		e.string(name)
+	}
+
+	e.uint16(len(p.Functions))
+	for _, fn := range p.functions() {
+		e.string(fn.Name)
+		e.uint16(fn.Params)
+		e.uint16(fn.Locals)
+		e.string(string(fn.Code))
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// Decode reads a program in the serialized bytecode format, checking that
+// it's safe to run
+func Decode(r io.Reader) (*Program, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	if magic := d.bytes(len(Magic)); d.err == nil && string(magic) != Magic {
+		return nil, errors.New("not a meego bytecode file")
+	}
+	if version := d.byte(); d.err == nil && version != Version {
+		return nil, fmt.Errorf("unsupported bytecode version %d", version)
+	}
+
+	p := &Program{}
+	for n := d.uint16(); n > 0 && d.err == nil; n-- {
+		switch tag := d.byte(); tag {
+		case tagInt:
+			var val int32
+			d.get(&val)
+			p.Constants = append(p.Constants, interp.Int(val))
+		case tagString:
+			p.Constants = append(p.Constants, interp.String(d.string()))
+		case tagBool:
+			var val bool
+			d.get(&val)
+			p.Constants = append(p.Constants, interp.Bool(val))
+		case tagNothing:
+			p.Constants = append(p.Constants, interp.Nothing{})
+		default:
+			d.fail(fmt.Errorf("unknown constant tag %d", tag))
+		}
+	}
+
+	for n := d.uint16(); n > 0 && d.err == nil; n-- {
+		p.Methods = append(p.Methods, d.string())
+	}
+
+	count := d.uint16()
+	for i := 0; i <= count && d.err == nil; i++ {
+		fn := &Function{Name: d.string(), Params: d.uint16(), Locals: d.uint16()}
+		fn.Code = []byte(d.string())
+		if i == count {
+			p.Main = fn
+		} else {
+			p.Functions = append(p.Functions, fn)
+		}
+	}
+
+	if d.err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if d.err != nil {
+		return nil, d.err
+	}
+	return p, p.Verify()
+}
+
+// Verify checks that every instruction is known and its operand in range,
+// so that the VM can't index out of its tables
+func (p *Program) Verify() error {
+	for _, fn := range p.functions() {
+		if fn.Params > fn.Locals {
+			return fmt.Errorf("%s: more parameters than locals", fn.Name)
+		}
+
+		for offset := 0; offset < len(fn.Code); {
+			op := Opcode(fn.Code[offset])
+			def, ok := Lookup(op)
+			if !ok {
+				return fmt.Errorf("%s:%04d: unknown opcode %d", fn.Name, offset, op)
+			}
+			if offset+1+2*def.Operands > len(fn.Code) {
+				return fmt.Errorf("%s:%04d: truncated %s", fn.Name, offset, def.Name)
+			}
+
+			if def.Operands != 0 {
+				limit := map[Opcode]int{OpConstant: len(p.Constants),
+					OpGetLocal: fn.Locals, OpSetLocal: fn.Locals,
+					OpMethod: len(p.Methods), OpUnary: len(p.Methods),
+					OpCall: len(p.Functions),
+					OpJump: len(fn.Code) + 1, OpJumpIfFalse: len(fn.Code) + 1}[op]
+
+				if arg := operand(fn.Code, offset); arg >= limit {
+					return fmt.Errorf("%s:%04d: operand %d of %s out of range",
+						fn.Name, offset, arg, def.Name)
+				}
+			}
+			offset += 1 + 2*def.Operands
+		}
+	}
+
+	return nil
+}
+
+// Writer that remembers the first error
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) put(data interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.BigEndian, data)
+	}
+}
+
+func (e *encoder) byte(b byte)    { e.put(b) }
+func (e *encoder) bytes(b []byte) { e.put(b) }
+func (e *encoder) uint16(n int)   { e.put(uint16(n)) }
+func (e *encoder) string(s string) {
+	e.put(uint32(len(s)))
+	e.put([]byte(s))
+}
+
+// Reader that remembers the first error
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) get(data interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.r, binary.BigEndian, data)
+	}
+}
+
+func (d *decoder) byte() byte {
+	var b byte
+	d.get(&b)
+	return b
+}
+
+func (d *decoder) bytes(n int) []byte {
+	b := make([]byte, n)
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, b)
+	}
+	return b
+}
+
+func (d *decoder) uint16() int {
+	var n uint16
+	d.get(&n)
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	var n uint32
+	d.get(&n)
+	if d.err != nil {
+		return ""
+	}
+
+	// Don't trust the length with a huge allocation
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, d.r, int64(n)); err != nil {
+		d.fail(err)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/aniketp/meego/src/interp"
+)
+
+// Deepest call stack before giving up, rather than running out of memory
+const MaxFrames = 1 << 16
+
+/*VM : stack machine running a compiled program */
+type VM struct {
+	program *Program
+	out     *bufio.Writer
+	stack   []interp.Value
+	frames  int
+}
+
+// Run a compiled program, writing what it prints to out
+func Run(p *Program, out io.Writer) (err error) {
+	vm := &VM{program: p, out: bufio.NewWriter(out)}
+	defer func() {
+		// Bytecode that passed Verify may still be ill-typed
+		if r := recover(); r != nil {
+			vm.out.Flush()
+			if e, ok := r.(interp.RuntimeError); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("invalid bytecode: %v", r)
+			}
+		}
+	}()
+
+	err = vm.call(p.Main)
+	if flushErr := vm.out.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// Execute fn on the arguments at the top of the stack, replacing them with
+// its result
+func (vm *VM) call(fn *Function) error {
+	if vm.frames == MaxFrames {
+		return interp.RuntimeError("stack overflow in " + fn.Name)
+	}
+	vm.frames++
+	defer func() { vm.frames-- }()
+
+	// The arguments become the first locals of the frame
+	base := len(vm.stack) - fn.Params
+	for i := fn.Params; i < fn.Locals; i++ {
+		vm.stack = append(vm.stack, interp.Nothing{})
+	}
+
+	code := fn.Code
+	for ip := 0; ip < len(code); {
+		op := Opcode(code[ip])
+		arg := 0
+		if definitions[op].Operands != 0 {
+			arg = int(binary.BigEndian.Uint16(code[ip+1:]))
+			ip += 3
+		} else {
+			ip++
+		}
+
+		switch op {
+		case OpConstant:
+			vm.push(vm.program.Constants[arg])
+		case OpGetLocal:
+			vm.push(vm.stack[base+arg])
+		case OpSetLocal:
+			vm.stack[base+arg] = vm.pop()
+		case OpPop:
+			vm.pop()
+		case OpMethod:
+			right, left := vm.pop(), vm.pop()
+			name := vm.program.Methods[arg]
+			method, ok := interp.Methods[left.Type()][name]
+			if !ok {
+				return fmt.Errorf("method %s does not exist for type %s", name, left.Type())
+			}
+			vm.push(method(left, right))
+		case OpUnary:
+			right := vm.pop()
+			name := vm.program.Methods[arg]
+			method, ok := interp.Methods[right.Type()][name]
+			if !ok {
+				return fmt.Errorf("method %s does not exist for type %s", name, right.Type())
+			}
+			vm.push(method(right, nil))
+		case OpPrint:
+			vm.out.WriteString(vm.pop().String() + "\n")
+		case OpCall:
+			if err := vm.call(vm.program.Functions[arg]); err != nil {
+				return err
+			}
+		case OpReturn:
+			result := vm.pop()
+			vm.stack = append(vm.stack[:base], result)
+			return nil
+		case OpJump:
+			ip = arg
+		case OpJumpIfFalse:
+			if !vm.pop().(interp.Bool) {
+				ip = arg
+			}
+		default:
+			return fmt.Errorf("unknown opcode %d in %s", op, fn.Name)
+		}
+	}
+
+	// Only the top-level statements run off the end
+	vm.stack = vm.stack[:base]
+	return nil
+}
+
+func (vm *VM) push(val interp.Value) {
+	vm.stack = append(vm.stack, val)
+}
+
+func (vm *VM) pop() interp.Value {
+	val := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return val
+}
@@ -57,3 +57,37 @@ func TestAST(t *testing.T) {
 	}
 
 }
+
+func TestASTElseIf(t *testing.T) {
+	program := Parse(`
+		if (a) {
+		} else if (b) {
+		} else if (c) {
+		} else {
+		}
+		if (d) {
+		}`)
+
+	// The chain nests to the right, ending with the plain else
+	var conds []string
+	var node ast.Statement = program.Statements[0]
+	for {
+		stmt, ok := node.(*ast.IfStatement)
+		if !ok {
+			break
+		}
+		conds = append(conds, stmt.Condition.(*ast.Identifier).Value)
+		node = stmt.Alternative
+	}
+
+	if fmt.Sprint(conds) != "[a b c]" {
+		t.Fatalf("wrong chain of conditions %v", conds)
+	}
+	if _, ok := node.(*ast.BlockStatement); !ok {
+		t.Fatalf("chain should end with an else block, got=%T", node)
+	}
+
+	if alt := program.Statements[1].(*ast.IfStatement).Alternative; alt != nil {
+		t.Fatalf("if without else has alternative %T", alt)
+	}
+}
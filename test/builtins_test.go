@@ -0,0 +1,79 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/checker"
+	"github.com/aniketp/meego/src/codegen"
+	"github.com/aniketp/meego/src/interp"
+)
+
+// The checked-in runtime is what codegen generates from checker.Builtins
+func TestRuntimeUpToDate(t *testing.T) {
+	runtime, err := ioutil.ReadFile("../input/Builtins.cpp")
+	check(err)
+
+	if !bytes.Equal(runtime, codegen.Runtime()) {
+		t.Fatal("input/Builtins.cpp is stale, run go generate ./src/codegen")
+	}
+}
+
+// Every stage agrees with the specification of the builtin types
+func TestBuiltinsConsistency(t *testing.T) {
+	runtime := string(codegen.Runtime())
+
+	for _, kind := range checker.Builtins {
+		zero, ok := interp.Zero[kind.Name]
+		if !ok || fmt.Sprintf("%#v", zero.String()) != fmt.Sprintf("%#v", strings.Trim(kind.Zero, `"`)) {
+			t.Errorf("interpreter: zero value of %s doesn't match the spec", kind.Name)
+		}
+
+		class := runtime[strings.Index(runtime, "class "+kind.Name+":"):]
+		class = class[:strings.Index(class, "};")]
+
+		for _, method := range kind.Methods {
+			sig, ok := checker.GetMethod(kind.Name, method.Method)
+			if !ok || sig.Return != method.Return {
+				t.Errorf("TypeTable: %s.%s doesn't match the spec", kind.Name, method.Method)
+			}
+
+			// PRINT is a builtin function rather than an operator
+			if method.Method == checker.PRINT {
+				continue
+			}
+
+			if _, ok := interp.Methods[kind.Name][method.Method]; !ok {
+				t.Errorf("interpreter: %s.%s is missing", kind.Name, method.Method)
+			}
+			if !strings.Contains(class, " "+method.Method+"(") {
+				t.Errorf("C++ runtime: %s.%s is missing", kind.Name, method.Method)
+			}
+		}
+	}
+
+	// Nothing is implemented that the spec doesn't define
+	for kind, methods := range interp.Methods {
+		for name := range methods {
+			if !checker.MethodExist(kind, name) {
+				t.Errorf("interpreter: %s.%s isn't in the spec", kind, name)
+			}
+		}
+	}
+
+	// Every operator of the grammar calls a method of some type
+	for _, table := range []map[string]string{checker.Operators, checker.Prefixes} {
+		for operator, name := range table {
+			found := false
+			for kind := range checker.TypeTable {
+				found = found || checker.MethodExist(kind, name)
+			}
+			if !found {
+				t.Errorf("operator %s calls %s, which no type defines", operator, name)
+			}
+		}
+	}
+}
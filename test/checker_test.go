@@ -5,8 +5,7 @@ import (
 
 	"github.com/aniketp/meego/src/ast"
 	"github.com/aniketp/meego/src/checker"
-	"github.com/aniketp/meego/src/lexer"
-	"github.com/aniketp/meego/src/parser"
+	"github.com/aniketp/meego/src/compiler"
 )
 
 type Test struct {
@@ -23,7 +22,13 @@ func TestOperations(t *testing.T) {
 		{`5 < 10;`, true},
 		{`true and true;`, true},
 		{`4 and 2;`, false},
-		{`true or false;`, true}}
+		{`true or false;`, true},
+		{`-5 + 2;`, true},
+		{`!true or not false;`, true},
+		{`-"five";`, false},
+		{`-true;`, false},
+		{`!1;`, false},
+		{`not "a";`, false}}
 
 	runTests(tests, t)
 }
@@ -50,6 +55,332 @@ func TestIdents(t *testing.T) {
 	runTests(tests, t)
 }
 
+func TestErrorPropagation(t *testing.T) {
+	tests := []Test{
+		// Errors within initializers and assignments
+		{`let x = 5 + "5";`, false},
+		{`let x = y;`, false},
+		{
+			`let x = 5;
+			x = 5 + "5";`, false},
+		{
+			`let x = 5;
+			x = y;`, false},
+		// Errors within the condition, block and alternative of an if
+		{
+			`if (5 + "5" == 10) {
+			} else {
+			}`, false},
+		{
+			`if (true) {
+				let x = 5 + "5";
+			} else {
+			}`, false},
+		{
+			`if (true) {
+			} else {
+				let x = 5;
+				x = "five";
+			}`, false},
+		// Errors within an else if chain
+		{
+			`if (true) {
+			} else if (5) {
+			}`, false},
+		{
+			`if (true) {
+			} else if (false) {
+				let x = 5 + "5";
+			} else {
+			}`, false},
+		{
+			`if (true) {
+			} else if (false) {
+			} else {
+				let x = 5;
+				x = "five";
+			}`, false},
+		// Nested ifs
+		{
+			`if (true) {
+				if (false) {
+				} else {
+					let x = true and 5;
+				}
+			} else {
+			}`, false},
+		{
+			`if (true) {
+				for true {
+					let x = 1;
+					if (x < 2) {
+						x = "one";
+					} else {
+					}
+				}
+			} else {
+			}`, false},
+		// Within function bodies and call arguments
+		{
+			`func f(n Int) Int {
+				if (n < 1) {
+					let m = n + "1";
+				} else {
+				}
+				return n;
+			}`, false},
+		{
+			`func f(n Int) Int {
+				return n;
+			}
+			let x = f(5 + "5");`, false},
+		{
+			`let x = 5;
+			if (x < 10) {
+				x = x + 1;
+			} else {
+				x = x - 1;
+			}`, true},
+		{
+			`let x = 5;
+			if (x < 10) {
+				x = x + 1;
+			} else if (x < 20) {
+				x = x - 1;
+			}`, true}}
+
+	runTests(tests, t)
+}
+
+func TestDiagnostics(t *testing.T) {
+	// Indented with spaces, as gocc counts tabs as several columns
+	const input = `func f(n Int) Int {
+  let m = n + "1";
+  return m;
+}
+let x = y;
+let z = 1;
+if (5) {
+  z = "five";
+} else {
+}
+break;`
+
+	expected := []string{
+		`test.meego:2:11: error: Incorrect types for operation`,
+		`test.meego:5:9: error: undefined: y`,
+		`test.meego:7:5: error: Condition not of Boolean type`,
+		`test.meego:8:3: error: Invalid type assignment`,
+		`test.meego:11:1: error: break is not in a loop or switch`,
+	}
+
+	diags, err := stringToDiagnostics(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got=%d:\n%s", len(expected),
+			len(diags), diags.Error())
+	}
+
+	for i, diag := range diags {
+		if diag.String() != expected[i] {
+			t.Fatalf("diagnostic %d: expected %q, got=%q", i, expected[i],
+				diag.String())
+		}
+	}
+
+	codes := []string{checker.TYPE_MISMATCH, checker.UNDEFINED,
+		checker.TYPE_MISMATCH, checker.TYPE_MISMATCH, checker.MISPLACED_BRANCH}
+	for i, diag := range diags {
+		if diag.Code != codes[i] {
+			t.Fatalf("diagnostic %d: expected code %q, got=%q", i, codes[i], diag.Code)
+		}
+	}
+
+	// Ranges cover the whole offending node
+	if end := diags[1].End; end.Line != 5 || end.Column != 10 {
+		t.Fatalf("wrong end of range for 'y': %d:%d", end.Line, end.Column)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	tests := []Test{
+		// Parameters don't leak into top-level code
+		{
+			`func id(x Int) Int {
+				return x;
+			}
+			x = 5;`, false},
+		// Parameters of different functions are independent
+		{
+			`func inc(x Int) Int {
+				return x + 1;
+			}
+
+			func greet(x String) String {
+				return "hi " + x;
+			}`, true},
+		// Functions don't see top-level declarations
+		{
+			`func get() Int {
+				return y;
+			}
+			let y = 5;`, false},
+		// A block's declarations end with the block
+		{
+			`if (true) {
+				let y = 5;
+			} else {
+			}
+			y = 6;`, false},
+		// Inner blocks may shadow outer declarations, with their own type
+		{
+			`let x = 5;
+			if (true) {
+				let x = "shadow";
+				x = "again";
+			} else {
+			}
+			x = 6;`, true},
+		// Assignments resolve to the nearest declaration
+		{
+			`let x = 5;
+			for false {
+				let x = "shadow";
+				x = 6;
+			}`, false},
+		// Outer declarations are visible in inner blocks
+		{
+			`let x = 5;
+			if (true) {
+				x = 6;
+			} else {
+				x = 7;
+			}`, true},
+		// No redeclaration within the same scope
+		{
+			`for false {
+				let x = 5;
+				let x = 6;
+			}`, false},
+		// Parameters can't be redeclared in the function body
+		{
+			`func f(x Int) Int {
+				let x = 5;
+				return x;
+			}`, false},
+		// but may be shadowed in a nested block
+		{
+			`func f(x Int) Int {
+				if (true) {
+					let x = "shadow";
+				} else {
+				}
+				return x;
+			}`, true},
+		// Loop variables are scoped to the loop
+		{
+			`for let i = 0; i < 3; i = i + 1 {
+				PRINT(i);
+			}
+			i = 5;`, false},
+		{
+			`for let i = 0; i < 3; i = i + 1 {
+				let i = "shadow";
+			}
+			let i = 5;`, true}}
+
+	runTests(tests, t)
+}
+
+type ErrorTest struct {
+	src string
+	err string // expected error, empty on success
+}
+
+func TestUndefined(t *testing.T) {
+	tests := []ErrorTest{
+		{`z + 1;`, "undefined: z"},
+		{`PRINT(z);`, "undefined: z"},
+		{`z = 1;`, "undefined: z"},
+		{`missing(1);`, "undefined: missing"},
+		{
+			`func get() Int {
+				return y;
+			}`, "undefined: y"},
+		{
+			`let x = 5;
+			PRINT(x);`, ""}}
+
+	runErrorTests(tests, t)
+}
+
+func TestUseBeforeDeclaration(t *testing.T) {
+	tests := []ErrorTest{
+		{
+			`PRINT(x);
+			let x = 5;`, "x used before declaration"},
+		{
+			`x = 4;
+			let x = 5;`, "x used before declaration"},
+		{
+			`for false {
+				PRINT(x);
+			}
+			let x = 5;`, "x used before declaration"},
+		{
+			`func f(n Int) Int {
+				PRINT(b);
+				let b = n;
+				return b;
+			}`, "b used before declaration"}}
+
+	runErrorTests(tests, t)
+}
+
+func TestCallNonFunction(t *testing.T) {
+	tests := []ErrorTest{
+		{
+			`let x = 5;
+			x(1);`, "cannot call non-function x (variable of type Int)"},
+		{
+			`func add(x Int, y Int) Int {
+				return x + y;
+			}
+			let add = 5;
+			add(1, 2);`, "cannot call non-function add (variable of type Int)"},
+		{
+			`func add(x Int, y Int) Int {
+				return x + y;
+			}
+			add(1, 2);`, ""}}
+
+	runErrorTests(tests, t)
+}
+
+func TestFunctionAsValue(t *testing.T) {
+	tests := []ErrorTest{
+		{
+			`func one() Int {
+				return 1;
+			}
+			one + 1;`, "cannot use function one as a value"},
+		{
+			`func one() Int {
+				return 1;
+			}
+			PRINT(one);`, "cannot use function one as a value"},
+		{
+			`func one() Int {
+				return 1;
+			}
+			one = 2;`, "cannot assign to function one"}}
+
+	runErrorTests(tests, t)
+}
+
 func TestFunctions(t *testing.T) {
 	tests := []Test{
 		{
@@ -63,7 +394,7 @@ func TestFunctions(t *testing.T) {
 				return x + y;
 			}
 
-			let z = add("test", 3);`, true},
+			let z = add("test", 3);`, false},
 		{
 			`func one() Int {
 				return "test";
@@ -73,6 +404,163 @@ func TestFunctions(t *testing.T) {
 	runTests(tests, t)
 }
 
+func TestRecursion(t *testing.T) {
+	tests := []Test{
+		{
+			`func fact(n Int) Int {
+				let r = 1;
+				if (n < 2) {
+					r = 1;
+				} else {
+					r = n * fact(n - 1);
+				}
+				return r;
+			}`, true},
+		{
+			`func first() Int {
+				return second();
+			}
+
+			func second() Int {
+				return 2;
+			}`, true},
+		{
+			`func ping(n Int) Int {
+				return pong(n);
+			}
+
+			func pong(n Int) Int {
+				return ping(n);
+			}`, true},
+		{
+			`func twice() Int {
+				return 1;
+			}
+
+			func twice() Int {
+				return 2;
+			}`, false},
+		{
+			`func fact(n Int) Int {
+				return fact("five");
+			}`, false}}
+
+	runTests(tests, t)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []Test{
+		{
+			`let x = 0;
+			for x < 10 {
+				x = x + 1;
+			}`, true},
+		{
+			`let sum = 0;
+			for let i = 0; i < 5; i = i + 1 {
+				sum = sum + i;
+			}`, true},
+		{
+			`for 5 {
+				PRINT(5);
+			}`, false},
+		{
+			`for let i = 0; i + 1; i = i + 1 {
+				PRINT(i);
+			}`, false},
+		{
+			`for let i = 0; i < 5; i = "five" {
+				PRINT(i);
+			}`, false},
+		{
+			`for true {
+				5 + "5";
+			}`, false}}
+
+	runTests(tests, t)
+}
+
+func TestBreakContinue(t *testing.T) {
+	tests := []Test{
+		{
+			`for true {
+				break;
+			}`, true},
+		{
+			`let x = 0;
+			for x < 10 {
+				x = x + 1;
+				if (x < 5) {
+					continue;
+				} else {
+					break;
+				}
+			}`, true},
+		{`break;`, false},
+		{`continue;`, false},
+		{
+			`func loop() Int {
+				break;
+				return 1;
+			}`, false},
+		{
+			`for true {
+				break;
+			}
+			continue;`, false}}
+
+	runTests(tests, t)
+}
+
+func TestTypeInfo(t *testing.T) {
+	res, err := compiler.Compile([]byte(`
+		func greet(name String) String {
+			return "hi " + name;
+		}
+		let n = 1 + 2;
+		let s = greet("x");
+		let b = n < 3;`), &compiler.Options{CheckOnly: true})
+	check(err)
+	info := res.Info
+
+	// Every expression has a type, down to the operands
+	types := map[string]string{}
+	for expr, kind := range info.Types {
+		if lit, ok := expr.(*ast.IntegerLiteral); ok {
+			types[lit.Value] = kind
+		}
+	}
+	if types["1"] != checker.INT_TYPE || types["3"] != checker.INT_TYPE {
+		t.Fatalf("missing the types of literals, got=%v", types)
+	}
+
+	for i, want := range []string{checker.INT_TYPE, checker.STRING_TYPE, checker.BOOL_TYPE} {
+		init := res.Program.Statements[i].(*ast.InitStatement)
+		if kind := info.TypeOf(init.Expr); kind != want {
+			t.Fatalf("statement %d: expected type %s, got=%s", i, want, kind)
+		}
+	}
+
+	// Identifiers resolve to their declaration
+	cond := res.Program.Statements[2].(*ast.InitStatement).Expr.(*ast.InfixExpression)
+	binding, ok := info.Binding(cond.Left)
+	if !ok || binding.Decl != res.Program.Statements[0] {
+		t.Fatalf("expected n to resolve to its let, got=%v", binding)
+	}
+
+	fn := res.Program.Functions[0].(*ast.FunctionStatement)
+	ret := fn.Body.Statements[0].(*ast.ReturnStatement).ReturnValue.(*ast.InfixExpression)
+	binding, ok = info.Binding(ret.Right)
+	if !ok || binding.Decl != fn || binding.Type != checker.STRING_TYPE {
+		t.Fatalf("expected name to resolve to a parameter of greet, got=%v", binding)
+	}
+
+	sig := info.Funcs["greet"]
+	if sig.Return != checker.STRING_TYPE || len(sig.Params) != 1 {
+		t.Fatalf("expected the signature of greet, got=%v", sig)
+	}
+}
+
 func runTests(tests []Test, t *testing.T) {
 	for i, test := range tests {
 		err := stringToChecker(test.src)
@@ -87,18 +575,172 @@ func runTests(tests []Test, t *testing.T) {
 	}
 }
 
+func runErrorTests(tests []ErrorTest, t *testing.T) {
+	for i, test := range tests {
+		diags, err := stringToDiagnostics(test.src)
+		if err != nil {
+			t.Fatalf("test %d fail: "+err.Error(), i)
+		}
+
+		if test.err == "" {
+			if diags.HasErrors() {
+				t.Fatalf("test %d fail: "+diags.Error(), i)
+			}
+		} else if !diags.HasErrors() {
+			t.Fatalf("test %d: expected error %q", i, test.err)
+		} else if diags[0].Message != test.err {
+			t.Fatalf("test %d: expected error %q, got=%q", i, test.err,
+				diags[0].Message)
+		}
+	}
+}
+
+func stringToDiagnostics(input string) (checker.Diagnostics, error) {
+	res, err := compiler.Compile([]byte(input),
+		&compiler.Options{File: "test.meego", CheckOnly: true})
+	if res.Program == nil {
+		return nil, err
+	}
+
+	return res.Diagnostics, nil
+}
+
 func stringToChecker(input string) error {
-	l := lexer.NewLexer([]byte(input))
-	p := parser.NewParser()
-	res, err := p.Parse(l)
-	if err != nil {
-		return err
+	_, err := compiler.Compile([]byte(input), &compiler.Options{CheckOnly: true})
+	return err
+}
+
+func TestTypedDeclarations(t *testing.T) {
+	tests := []ErrorTest{
+		{`let x Int = 5;`, ""},
+		{`let s String;
+		s = "later";`, ""},
+		{`let b Bool = 1 < 2;
+		b = false;`, ""},
+		{`let x Int = "five";`, "Cannot use a value of type String to initialize x of type Int"},
+		{`let x Int;
+		x = "five";`, "Invalid type assignment"},
+		{`let x Float;`, "Cannot declare a variable of type Float"},
+		{`let x Nothing = PRINT(1);`, "Cannot declare a variable of type Nothing"},
+		{`let x Int;
+		let x String;`, "Identifier already exists"},
+		// The declared type holds even when the value is bad
+		{`let x Int = y;
+		x = 1;`, "undefined: y"},
+		{`for let i Int = 0; i < 3; i = i + 1 {
+		}`, ""},
 	}
 
-	program, _ := res.(*ast.Program)
-	err = checker.Checker(program)
-	if err != nil {
-		return err
+	runErrorTests(tests, t)
+}
+
+func TestConstants(t *testing.T) {
+	tests := []ErrorTest{
+		{`const Limit = 100;`, ""},
+		{`const Limit Int = 100;
+		let x = Limit + 1;`, ""},
+		{`const Limit = 100;
+		Limit = 5;`, "cannot assign to constant Limit"},
+		{`const Greeting = "hello " + "world";
+		const Loud = Greeting != "";`, ""},
+		{`const Half = 100 / 2;
+		const Quarter = Half / 2;`, ""},
+		{`let n = 4;
+		const Limit = n * 2;`, "n is not a constant"},
+		{`func four() Int {
+			return 4;
+		}
+		const Limit = four();`, "Cannot call four in a constant"},
+		{`const Limit = 1 / 0;`, "Constant division by zero"},
+		{`const Limit = 1 / (1 - 1);`, "Constant division by zero"},
+		// The right operand of and/or is folded even when not needed
+		{`const Never = false and 1 / 0 > 0;`, "Constant division by zero"},
+		{`const A = false and (1 / 0 == 1);`, "Constant division by zero"},
+		{`const B = true or 1 / 0 == 1;`, "Constant division by zero"},
+		{`const C = false and 1 / 1 == 1;`, ""},
+		{`let v = true;
+		const Y = true or v;`, "v is not a constant"},
+		{`func f(n Int) Bool {
+			return n > 0;
+		}
+		const X = false and f(1);`, "Cannot call f in a constant"},
+		{`let n = 0;
+		const Z = false and (1 > 0 or n > 0);`, "n is not a constant"},
+		{`const Limit String = 100;`, "Cannot use a value of type Int to initialize Limit of type String"},
+		{`const Limit = 100;
+		const Limit = 200;`, "Identifier already exists"},
+		// A local variable shadows a constant
+		{`const Limit = 100;
+		if (true) {
+			let Limit = 5;
+			Limit = 6;
+		}`, ""},
+		{`const A = 1;
+		const B = 2 - 1;
+		switch 1 {
+		case A:
+		case B:
+		}`, "Duplicate case 1 in switch"},
 	}
-	return nil
+
+	runErrorTests(tests, t)
+}
+
+func TestNestedReturns(t *testing.T) {
+	tests := []ErrorTest{
+		{`func f(n Int) Int {
+			if (n < 1) {
+				return "s";
+			} else {
+			}
+			return n;
+		}`, "Incorrect return type"},
+		{`func f(n Int) Int {
+			if (n < 1) {
+				return 0;
+			} else if (n < 2) {
+				return true;
+			}
+			return n;
+		}`, "Incorrect return type"},
+		{`func f(n Int) Int {
+			for let i = 0; i < n; i = i + 1 {
+				return "s";
+			}
+			return n;
+		}`, "Incorrect return type"},
+		{`func f(n Int) Int {
+			switch n {
+			case 1:
+				return "one";
+			}
+			return n;
+		}`, "Incorrect return type"},
+		{`func f(n Int) Int {
+			if (n < 1) {
+				return 0;
+			} else {
+				for n > 5 {
+					return n - 1;
+				}
+			}
+			return n;
+		}`, ""},
+		{`return 1;`, "return is not in a function"},
+		{`if (true) {
+			return 1;
+		}`, "return is not in a function"},
+	}
+
+	runErrorTests(tests, t)
+}
+
+func TestPrintArguments(t *testing.T) {
+	tests := []ErrorTest{
+		{`PRINT(1);`, ""},
+		{`PRINT();`, "Incorrect number of function arguments"},
+		{`PRINT(1, 2);`, "Incorrect number of function arguments"},
+	}
+
+	runErrorTests(tests, t)
 }
@@ -3,8 +3,6 @@ package test
 import (
 	"strings"
 	"testing"
-
-	"github.com/aniketp/meego/src/codegen"
 )
 
 func TestGen(t *testing.T) {
@@ -79,6 +77,7 @@ func TestGen(t *testing.T) {
 				#include <iostream>
 				#include <string>
 				#include "Builtins.cpp"
+				Int add(Int y, Int x);
 				Int add(Int y, Int x) {
 					Int tmp_1 = x.PLUS(y);
 					return tmp_1;
@@ -113,13 +112,163 @@ func TestGen(t *testing.T) {
 					x = tmp_3;
 				}
 				return 0;
+				}`},
+		{
+			src: `
+				for let i = 0; i < 3; i = i + 1 {
+					PRINT(i);
+				}`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				{
+				Int tmp_1 = Int(0);
+				Int i = tmp_1;
+				while (true) {
+					Int tmp_2 = Int(3);
+					Bool tmp_3 = i.LT(tmp_2);
+					if ("true" != tmp_3.val) {
+						break;
+					}
+					{
+					Nothing tmp_4 = i.PRINT();
+					tmp_4;
+					}
+					Int tmp_5 = Int(1);
+					Int tmp_6 = i.PLUS(tmp_5);
+					i = tmp_6;
+				}
+				}
+				return 0;
+				}`},
+		{
+			src: `
+				let x = 0;
+				if (true) {
+					x = 5;
+				}`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				Int tmp_1 = Int(0);
+				Int x = tmp_1;
+				if("true" == Bool("true").val) {
+					Int tmp_2 = Int(5);
+					x = tmp_2;
+				}
+				return 0;
+				}`},
+		{
+			src: `
+				let x = 0;
+				let b = false;
+				if (b) {
+					x = 1;
+				} else if (x < 2) {
+					x = 2;
+				} else {
+					x = 3;
+				}`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				Int tmp_1 = Int(0);
+				Int x = tmp_1;
+				Bool b = Bool("false");
+				if("true" == b.val) {
+					Int tmp_2 = Int(1);
+					x = tmp_2;
+				} else if ("true" == [&]() -> Bool {
+					Int tmp_3 = Int(2);
+					Bool tmp_4 = x.LT(tmp_3);
+					return tmp_4;
+				}().val) {
+					Int tmp_5 = Int(2);
+					x = tmp_5;
+				} else {
+					Int tmp_6 = Int(3);
+					x = tmp_6;
+				}
+				return 0;
+				}`},
+		{
+			src: `
+				switch 2 {
+				case 1, 2:
+					PRINT("low");
+				default:
+					break;
+				}`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				{
+				Int tmp_1 = Int(2);
+				Int tmp_2 = tmp_1;
+				if ("true" == [&]() -> Bool {
+					Bool tmp_3 = Bool("false");
+					if ("true" != tmp_3.val) {
+						Int tmp_4 = Int(1);
+						tmp_3 = tmp_2.EQ(tmp_4);
+					}
+					if ("true" != tmp_3.val) {
+						Int tmp_5 = Int(2);
+						tmp_3 = tmp_2.EQ(tmp_5);
+					}
+					return tmp_3;
+				}().val) {
+					String tmp_6 = String("low");
+					Nothing tmp_7 = tmp_6.PRINT();
+					tmp_7;
+				} else {
+					goto break_1;
+				}
+				}
+				break_1:;
+				return 0;
+				}`},
+		{
+			src: `
+				let x Int;
+				let s String = "a";`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				Int x;
+				String tmp_1 = String("a");
+				String s = tmp_1;
+				return 0;
+				}`},
+		{
+			src: `
+				const Limit = 10 * 10;
+				const Name = "max " + "size";
+				const Over = Limit > 50;
+				PRINT(Limit + 1);`,
+			res: `
+				#include <iostream>
+				#include <string>
+				#include "Builtins.cpp"
+				int main() {
+				Int tmp_1 = Int(1);
+				Int tmp_2 = Int(100).PLUS(tmp_1);
+				Nothing tmp_3 = tmp_2.PRINT();
+				tmp_3;
+				return 0;
 				}`}}
 
 	for i, test := range tests {
-		program := Parse(test.src)
-		TypeCheck(program)
-		code := codegen.GenWrapper(program)
-		codeString := code.String()
+		codeString := Generate(test.src)
 		// remove spaces for comparison
 		for _, rep := range []string{" ", "\n", "\t"} {
 			codeString = strings.Replace(codeString, rep, "", -1)
@@ -133,50 +282,231 @@ func TestGen(t *testing.T) {
 	}
 }
 
-func TestOutPut(t *testing.T) {
-	tests := []struct {
-		src string
-		out string
-	}{
-		{
-			src: `let x = 5 + 5;
-				  PRINT(x);`,
-			out: "10"},
-		{
-			src: `let x = 10 > 4;
-				PRINT(x);`,
-			out: "true"},
-		{
-			src: `
-				let x = "hello";
-				let y = "world!";
-				let z = x + y;
-				PRINT(z);
-				`,
-			out: "helloworld!"},
-		{
-			src: `
-				func add(x Int, y Int) Int {
-					return x + y;
+// Programs along with what they print, shared by the C++ backend and the
+// interpreter
+var outputTests = []struct {
+	src string
+	out string
+}{
+	{
+		src: `let x = 5 + 5;
+			  PRINT(x);`,
+		out: "10"},
+	{
+		src: `let x = 10 > 4;
+			PRINT(x);`,
+		out: "true"},
+	{
+		src: `
+			let x = "hello";
+			let y = "world!";
+			let z = x + y;
+			PRINT(z);
+			`,
+		out: "helloworld!"},
+	{
+		src: `
+			func add(x Int, y Int) Int {
+				return x + y;
+			}
+			let a = add(1, 3);
+			PRINT(a);`,
+		out: "4"},
+	{
+		src: `
+			let x = 0;
+			if (true) {
+				x = 5;
+			} else {
+				x = 6;
+			}`,
+		out: ""},
+	{
+		src: `
+			func sign(n Int) String {
+				let s = "large";
+				if (n < 0) {
+					s = "negative";
+				} else if (n == 0) {
+					s = "zero";
+				} else if (n < 10) {
+					s = "small";
 				}
-				let a = add(1, 3);
-				PRINT(a);`,
-			out: "4"},
-		{
-			src: `
-				let x = 0;
-				if (true) {
-					x = 5;
+				return s;
+			}
+			PRINT(sign(-5));
+			PRINT(sign(0));
+			PRINT(sign(7));
+			PRINT(sign(42));
+			let n = 3;
+			if (n > 5) {
+				PRINT("unreachable");
+			}
+			if (n > 1) {
+				PRINT("first");
+			} else if (n > 2) {
+				PRINT("unreachable");
+			}`,
+		out: "negativezerosmalllargefirst"},
+	{
+		src: `
+			let x = 0;
+			for x < 3 {
+				PRINT(x);
+				x = x + 1;
+			}`,
+		out: "012"},
+	{
+		src: `
+			let sum = 0;
+			for let i = 1; i < 5; i = i + 1 {
+				sum = sum + i;
+			}
+			PRINT(sum);`,
+		out: "10"},
+	{
+		src: `
+			for let i = 0; i < 6; i = i + 1 {
+				if (i < 2) {
+					continue;
 				} else {
-					x = 6;
-				}`,
-			out: ""}}
+				}
+				if (4 < i) {
+					break;
+				} else {
+				}
+				PRINT(i);
+			}`,
+		out: "234"},
+	{
+		src: `
+			let i = 0;
+			for i < 5 {
+				i = i + 1;
+				if (i < 3) {
+					continue;
+				} else {
+				}
+				PRINT(i);
+			}`,
+		out: "345"},
+	{
+		src: `
+			func fact(n Int) Int {
+				let r = 1;
+				if (n < 2) {
+					r = 1;
+				} else {
+					r = n * fact(n - 1);
+				}
+				return r;
+			}
+			PRINT(fact(5));`,
+		out: "120"},
+	{
+		src: `
+			func isEven(n Int) Bool {
+				let even = true;
+				if (n < 1) {
+					even = true;
+				} else {
+					even = isOdd(n - 1);
+				}
+				return even;
+			}
 
-	for i, test := range tests {
-		program := Parse(test.src)
-		TypeCheck(program)
-		code := codegen.GenWrapper(program)
-		output := Compile(code)
+			func isOdd(n Int) Bool {
+				let odd = false;
+				if (n < 1) {
+					odd = false;
+				} else {
+					odd = isEven(n - 1);
+				}
+				return odd;
+			}
+			PRINT(isEven(4));
+			PRINT(isOdd(4));`,
+		out: "truefalse"},
+	{
+		src: `
+			let x = 1;
+			if (true) {
+				let x = "shadow";
+				PRINT(x);
+			} else {
+			}
+			PRINT(x);`,
+		out: "shadow1"},
+	{
+		src: `
+			func name(n Int) String {
+				let s = "";
+				switch n {
+				case 0:
+					s = "zero";
+				default:
+					s = "many";
+				case 1, 2:
+					s = "few";
+				}
+				return s;
+			}
+			PRINT(name(0));
+			PRINT(name(2));
+			PRINT(name(5));
+			switch "b" {
+			case "a":
+				PRINT("unreachable");
+			case "b":
+				PRINT("b");
+			}
+			switch 1 < 2 {
+			case false:
+				PRINT("unreachable");
+			case true:
+				PRINT("yes");
+			}
+			switch 3 {
+			case 1:
+				PRINT("unreachable");
+			}`,
+		out: "zerofewmanybyes"},
+	{
+		src: `
+			for let i = 0; i < 5; i = i + 1 {
+				switch i {
+				case 1:
+					continue;
+				case 3:
+					break;
+				default:
+					PRINT(i);
+					if (i > 3) {
+						break;
+					}
+					PRINT("after");
+				}
+				PRINT("next");
+			}`,
+		out: "0afternext2afternextnext4next"},
+	{
+		src: `
+			let n Int;
+			let s String;
+			let b Bool;
+			PRINT(n);
+			PRINT(s + "end");
+			PRINT(b);
+			let count Int;
+			for let i Int = 0; i < 4; i = i + 1 {
+				count = count + i;
+			}
+			PRINT(count);`,
+		out: "0endfalse6"}}
+
+func TestOutPut(t *testing.T) {
+	for i, test := range outputTests {
+		output := Compile(Generate(test.src))
 
 		for _, rep := range []string{" ", "\n", "\t"} {
 			output = strings.Replace(output, rep, "", -1)
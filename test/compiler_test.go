@@ -0,0 +1,98 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aniketp/meego/src/checker"
+	"github.com/aniketp/meego/src/compiler"
+)
+
+func TestCompilerResult(t *testing.T) {
+	res, err := compiler.Compile([]byte(`
+			func add(x Int, y Int) Int { return x + y; }
+			let z = add(1, 2);
+			PRINT(z);`), &compiler.Options{File: "add.meego"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if res.Program == nil || len(res.Program.Statements) != 2 {
+		t.Fatalf("expected the AST of the program, got=%v", res.Program)
+	}
+
+	if sig, ok := res.Info.Funcs["add"]; !ok || sig.Return != checker.INT_TYPE {
+		t.Fatalf("expected the signature of add, got=%v", res.Info.Funcs)
+	}
+
+	if !strings.Contains(res.Code, "Int add(") {
+		t.Fatalf("expected the generated C++, got=%q", res.Code)
+	}
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		code string
+	}{
+		{`let x = ;`, checker.SYNTAX_ERROR},
+		{`let x = 5 $ 3;`, checker.LEXICAL_ERROR},
+		{`let x = y;`, checker.UNDEFINED},
+		{`let x = 5 + "a";`, checker.TYPE_MISMATCH},
+	}
+
+	for i, test := range tests {
+		res, err := compiler.Compile([]byte(test.src), &compiler.Options{File: "test.meego"})
+		if err == nil {
+			t.Fatalf("test %d: expected an error", i)
+		}
+
+		if len(res.Diagnostics) == 0 || res.Diagnostics[0].Code != test.code {
+			t.Fatalf("test %d: expected a %s diagnostic, got=%v", i, test.code,
+				res.Diagnostics)
+		}
+
+		if res.Code != "" {
+			t.Fatalf("test %d: expected no C++ for an erroneous program", i)
+		}
+	}
+}
+
+// Compilations share no state, so they can run in parallel (run with -race)
+func TestCompileConcurrently(t *testing.T) {
+	sources := []string{`let x = y;`, `let x = 5 + "a";`, `break;`}
+	for _, test := range outputTests {
+		sources = append(sources, test.src)
+	}
+
+	// What each program compiles to when compiled on its own
+	expected := make([]string, len(sources))
+	for i, src := range sources {
+		res, _ := compiler.Compile([]byte(src), nil)
+		expected[i] = res.Code + res.Diagnostics.Error()
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*len(sources))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, src := range sources {
+				res, _ := compiler.Compile([]byte(src), nil)
+				if got := res.Code + res.Diagnostics.Error(); got != expected[i] {
+					errs <- fmt.Errorf("program %d: wanted %q, got=%q", i, expected[i], got)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
@@ -2,17 +2,14 @@ package test
 
 import (
 	"bytes"
-	"fmt"
 	"io/ioutil"
-	"os"
 	"os/exec"
-	"path/filepath"
 
 	"github.com/aniketp/meego/src/ast"
-	"github.com/aniketp/meego/src/checker"
-	"github.com/aniketp/meego/src/codegen"
-	"github.com/aniketp/meego/src/lexer"
-	"github.com/aniketp/meego/src/parser"
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+	"github.com/aniketp/meego/src/toolchain"
+	"github.com/aniketp/meego/src/vm"
 )
 
 func check(err error) {
@@ -22,37 +19,53 @@ func check(err error) {
 }
 
 func Parse(input string) *ast.Program {
-	l := lexer.NewLexer([]byte(input))
-	p := parser.NewParser()
+	program, diags := compiler.Parse("", []byte(input))
+	if diags != nil {
+		panic(diags)
+	}
+	return program
+}
 
-	node, err := p.Parse(l)
+// Generate the C++ of a program that must compile without errors
+func Generate(input string) string {
+	res, err := compiler.Compile([]byte(input), nil)
 	check(err)
-	program, _ := node.(*ast.Program)
-	return program
+	return res.Code
 }
 
-func TypeCheck(program *ast.Program) {
-	err := checker.Checker(program)
+// Run a program that must compile without errors through the interpreter
+func Interpret(input string) string {
+	res, err := compiler.Compile([]byte(input), &compiler.Options{CheckOnly: true})
 	check(err)
+
+	var out bytes.Buffer
+	check(interp.Run(res.Program, res.Info, &out))
+	return out.String()
 }
 
-func Compile(code bytes.Buffer) string {
-	f, err := os.Create("../input/main.cpp")
+// Compile a program that must compile without errors to bytecode
+func Bytecode(input string) *vm.Program {
+	res, err := compiler.Compile([]byte(input), &compiler.Options{CheckOnly: true})
 	check(err)
-	defer f.Close()
 
-	f.Write(code.Bytes())
+	program, err := vm.Compile(res.Program)
+	check(err)
+	return program
+}
 
+func RunBytecode(program *vm.Program) string {
 	var out bytes.Buffer
-	cmd1 := exec.Command("g++", "-std=c++11", "-o", "apple", "../input/main.cpp",
-		"../input/Builtins.cpp")
-	cmd1.Stderr = &out
-	err = cmd1.Run()
+	check(vm.Run(program, &out))
+	return out.String()
+}
 
-	// Check if output was a valid one
-	if len(out.String()) != 0 {
-		panic(fmt.Sprintf("error: %s", out.String()))
-	}
+func Compile(code string) string {
+	runtime, err := ioutil.ReadFile("../input/Builtins.cpp")
+	check(err)
+
+	// Warnings don't fail the compilation, only a non-zero exit status does
+	_, err = compiler.Build([]byte(code), runtime, "./apple", toolchain.Default())
+	check(err)
 
 	// Now, execute the resulting 'apple' binary
 	cmd := exec.Command("./apple")
@@ -63,23 +76,4 @@ func Compile(code bytes.Buffer) string {
 
 	// This is the generated output of our transpiled program
 	return outb.String()
-
-}
-
-func main() {
-	if len(os.Args) < 2 {
-		panic("Provide a valid file name")
-	}
-
-	path := os.Args[1]
-	absPath, _ := filepath.Abs(path)
-	input, err := ioutil.ReadFile(absPath)
-	check(err)
-
-	program := Parse(string(input))
-	TypeCheck(program)
-	// Generate vanilla C++
-	code := codegen.GenWrapper(program)
-	result := Compile(code)
-	fmt.Println(result)
 }
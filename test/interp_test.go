@@ -0,0 +1,63 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+)
+
+func TestInterp(t *testing.T) {
+	for i, test := range outputTests {
+		output := Interpret(test.src)
+
+		// Both backends must print exactly the same
+		expected := Compile(Generate(test.src))
+		if output != expected {
+			t.Fatalf("test [%d] failed wanted %q, got=%q", i, expected, output)
+		}
+	}
+}
+
+func TestInterpValues(t *testing.T) {
+	tests := []struct {
+		src string
+		out string
+	}{
+		{`PRINT(2 * 3 - 1);`, "5\n"},
+		{`PRINT(4 > 4);`, "false\n"},
+		{`PRINT(3 == 3);`, "true\n"},
+		{`PRINT("a" + "b");`, "ab\n"},
+		{`PRINT(2147483647 + 1);`, "-2147483648\n"},
+		{`
+			func greet(name String) Nothing {
+				PRINT("hi " + name);
+			}
+			greet("x");`, "hi x\n"},
+	}
+
+	for i, test := range tests {
+		output := Interpret(test.src)
+		if output != test.out {
+			t.Fatalf("test [%d] failed wanted %q, got=%q", i, test.out, output)
+		}
+	}
+}
+
+func TestInterpStackOverflow(t *testing.T) {
+	src := `
+		func f(n Int) Int {
+			return f(n + 1);
+		}
+		PRINT(f(0));`
+
+	res, err := compiler.Compile([]byte(src), &compiler.Options{CheckOnly: true})
+	check(err)
+
+	var out bytes.Buffer
+	err = interp.Run(res.Program, res.Info, &out)
+	if err == nil || err.Error() != "runtime error: stack overflow" {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+	"github.com/aniketp/meego/src/vm"
+)
+
+// Every operator the grammar accepts, on every type that defines it
+var operatorTests = []struct {
+	expr string
+	out  string
+}{
+	// Int
+	{`7 + 5`, "12"},
+	{`7 - 5`, "2"},
+	{`7 * 5`, "35"},
+	{`7 / 2`, "3"},
+	{`-7 / 2`, "-3"},
+	{`7 < 5`, "false"},
+	{`5 < 7`, "true"},
+	{`7 > 5`, "true"},
+	{`5 > 5`, "false"},
+	{`5 <= 5`, "true"},
+	{`7 <= 5`, "false"},
+	{`5 >= 5`, "true"},
+	{`5 >= 7`, "false"},
+	{`5 == 5`, "true"},
+	{`5 == 7`, "false"},
+	{`5 != 7`, "true"},
+	{`5 != 5`, "false"},
+	{`-5`, "-5"},
+
+	// String
+	{`"ab" + "cd"`, "abcd"},
+	{`"ab" == "ab"`, "true"},
+	{`"ab" == "cd"`, "false"},
+	{`"ab" != "cd"`, "true"},
+	{`"ab" != "ab"`, "false"},
+
+	// Bool
+	{`true and true`, "true"},
+	{`true and false`, "false"},
+	{`false and true`, "false"},
+	{`false and false`, "false"},
+	{`true or false`, "true"},
+	{`false or true`, "true"},
+	{`false or false`, "false"},
+	{`true == true`, "true"},
+	{`true == false`, "false"},
+	{`true != false`, "true"},
+	{`false != false`, "false"},
+	{`!true`, "false"},
+	{`not false`, "true"},
+}
+
+func TestOperators(t *testing.T) {
+	var src strings.Builder
+	var expected strings.Builder
+	for _, test := range operatorTests {
+		src.WriteString("PRINT(" + test.expr + ");\n")
+		expected.WriteString(test.out + "\n")
+	}
+
+	outputs := map[string]string{
+		"C++":         Compile(Generate(src.String())),
+		"interpreter": Interpret(src.String()),
+		"VM":          RunBytecode(Bytecode(src.String())),
+	}
+
+	for backend, output := range outputs {
+		got := strings.Split(output, "\n")
+		for i, test := range operatorTests {
+			if i >= len(got) || got[i] != test.out {
+				t.Fatalf("%s: %s: wanted %q, got=%q", backend, test.expr, test.out, output)
+			}
+		}
+	}
+}
+
+func TestOperatorTypes(t *testing.T) {
+	tests := []Test{
+		// Comparisons are Bool, whatever they compare
+		{`let x = 1 == 1; x = true;`, true},
+		{`let x = "a" != "b"; x = false;`, true},
+		{`let x = 1 <= 2 and 2 >= 1; x = true;`, true},
+		{`let x = 1 == 1; x = 1;`, false},
+		{`let x = 4 / 2; x = 1;`, true},
+		{`"a" - "b";`, false},
+		{`"a" / "b";`, false},
+		{`"a" < "b";`, false},
+		{`true + false;`, false},
+		{`true <= false;`, false},
+		{`1 and 2;`, false},
+		{`1 != "1";`, false}}
+
+	runTests(tests, t)
+}
+
+func TestDivisionByZero(t *testing.T) {
+	src := `PRINT(1);
+	let zero = 0;
+	PRINT(1 / zero);`
+
+	res, err := compiler.Compile([]byte(src), &compiler.Options{CheckOnly: true})
+	check(err)
+
+	var out bytes.Buffer
+	err = interp.Run(res.Program, res.Info, &out)
+	if err == nil || err.Error() != "runtime error: division by zero" || out.String() != "1\n" {
+		t.Fatalf("interpreter: got %q, %v", out.String(), err)
+	}
+
+	program, err := vm.Compile(res.Program)
+	check(err)
+
+	out.Reset()
+	err = vm.Run(program, &out)
+	if err == nil || err.Error() != "runtime error: division by zero" || out.String() != "1\n" {
+		t.Fatalf("VM: got %q, %v", out.String(), err)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	// Each operand prints its name, so the output shows which were evaluated
+	src := `
+		func yes(name String) Bool {
+			PRINT(name);
+			return true;
+		}
+		func no(name String) Bool {
+			PRINT(name);
+			return false;
+		}
+		PRINT(no("a") and yes("b"));
+		PRINT(yes("c") and no("d"));
+		PRINT(yes("e") or yes("f"));
+		PRINT(no("g") or yes("h"));
+		PRINT(no("i") and yes("j") or yes("k"));
+		PRINT(yes("l") or no("m") and no("n"));
+		let x = 0;
+		if (x > 0 and 10 / x > 1) {
+			PRINT("unreachable");
+		} else {
+		}
+		PRINT(x == 0 or 10 / x > 1);`
+	expected := "a\nfalse\nc\nd\nfalse\ne\ntrue\ng\nh\ntrue\ni\nk\ntrue\nl\ntrue\ntrue\n"
+
+	outputs := map[string]string{
+		"C++":         Compile(Generate(src)),
+		"interpreter": Interpret(src),
+		"VM":          RunBytecode(Bytecode(src)),
+	}
+
+	for backend, output := range outputs {
+		if output != expected {
+			t.Fatalf("%s: wanted %q, got=%q", backend, expected, output)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/ast"
+)
+
+// Fully parenthesized form of an expression, showing how it was grouped
+func group(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return fmt.Sprintf("(%s %s %s)", group(expr.Left), expr.Operator, group(expr.Right))
+	case *ast.PrefixExpression:
+		if expr.Operator == "not" {
+			return fmt.Sprintf("(not %s)", group(expr.Right))
+		}
+		return fmt.Sprintf("(%s%s)", expr.Operator, group(expr.Right))
+	case *ast.FunctionCall:
+		args := make([]string, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = group(arg)
+		}
+		return fmt.Sprintf("%s(%s)", expr.Name, strings.Join(args, ", "))
+	}
+
+	return expr.TokenLiteral()
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		src   string
+		group string
+	}{
+		// Tiers, from the tightest binding: * /, + -, comparisons, and, or
+		{`1 + 2 * 3`, `(1 + (2 * 3))`},
+		{`1 * 2 + 3`, `((1 * 2) + 3)`},
+		{`2 * 3 + 4 * 5`, `((2 * 3) + (4 * 5))`},
+		{`1 + 2 < 4`, `((1 + 2) < 4)`},
+		{`4 > 1 + 2`, `(4 > (1 + 2))`},
+		{`1 + 2 < 4 and true`, `(((1 + 2) < 4) and true)`},
+		{`true and 1 < 2`, `(true and (1 < 2))`},
+		{`a or b and c`, `(a or (b and c))`},
+		{`a and b or c`, `((a and b) or c)`},
+		{`1 + 2 == 3 or 1 < 0`, `(((1 + 2) == 3) or (1 < 0))`},
+		{`x <= 1 and y >= 2 or z != 3`, `(((x <= 1) and (y >= 2)) or (z != 3))`},
+
+		// Left associativity within a tier
+		{`1 - 2 - 3`, `((1 - 2) - 3)`},
+		{`8 / 4 / 2`, `((8 / 4) / 2)`},
+		{`1 - 2 + 3`, `((1 - 2) + 3)`},
+		{`1 < 2 == true`, `((1 < 2) == true)`},
+		{`a and b and c`, `((a and b) and c)`},
+		{`a or b or c`, `((a or b) or c)`},
+
+		// Unary operators bind tighter than any binary one
+		{`-2 * 3`, `((-2) * 3)`},
+		{`1 - -2`, `(1 - (-2))`},
+		{`- -1`, `(-(-1))`},
+		{`-(2 + 3)`, `(-(2 + 3))`},
+		{`!a and b`, `((!a) and b)`},
+		{`not a or b`, `((not a) or b)`},
+		{`!(a and b)`, `(!(a and b))`},
+		{`not !a`, `(not (!a))`},
+
+		// Parentheses and calls
+		{`2 * (3 + 4)`, `(2 * (3 + 4))`},
+		{`(a or b) and c`, `((a or b) and c)`},
+		{`f(1 + 2 * 3) * 2`, `(f((1 + (2 * 3))) * 2)`},
+		{`"a" + "b" + "c"`, `(("a" + "b") + "c")`},
+	}
+
+	for i, test := range tests {
+		program := Parse(test.src + ";")
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		if result := group(stmt.Expression); result != test.group {
+			t.Fatalf("test [%d] %s: wanted %s, got=%s", i, test.src, test.group, result)
+		}
+	}
+}
+
+func TestPrecedenceOutput(t *testing.T) {
+	tests := []struct {
+		expr string
+		out  string
+	}{
+		{`1 + 2 * 3`, "7"},
+		{`2 * 3 + 4 * 5`, "26"},
+		{`10 - 4 - 3`, "3"},
+		{`10 - 2 * 3 - 1`, "3"},
+		{`2 * (3 + 4)`, "14"},
+		{`1 + 2 < 4`, "true"},
+		{`2 * 3 > 3 + 4`, "false"},
+		{`1 + 2 == 3`, "true"},
+		{`1 + 2 < 4 and 4 > 1 + 2`, "true"},
+		{`false and true or true`, "true"},
+		{`10 - 6 / 2 >= 7`, "true"},
+		{`"a" + "b" + "c"`, "abc"},
+		{`-5`, "-5"},
+		{`-(2 + 3) * 2`, "-10"},
+		{`- -7 + 1`, "8"},
+		{`!true`, "false"},
+		{`not false`, "true"},
+		{`!(1 < 2)`, "false"},
+	}
+
+	var src strings.Builder
+	var expected strings.Builder
+	for _, test := range tests {
+		src.WriteString("PRINT(" + test.expr + ");\n")
+		expected.WriteString(test.out + "\n")
+	}
+
+	// Every backend groups and evaluates them the same
+	outputs := map[string]string{
+		"C++":         Compile(Generate(src.String())),
+		"interpreter": Interpret(src.String()),
+		"VM":          RunBytecode(Bytecode(src.String())),
+	}
+
+	for backend, output := range outputs {
+		if output != expected.String() {
+			t.Fatalf("%s: wanted %q, got=%q", backend, expected.String(), output)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/repl"
+)
+
+func TestREPL(t *testing.T) {
+	tests := []struct {
+		input string
+		out   string
+	}{
+		{"let x = 5", "x : Int = 5"},
+		{"x + 3", "8 : Int"},
+		{":type x < 3", "Bool"},
+		// Errors don't end the session, nor declare anything
+		{"let y = z;", "1:9: error: undefined: z"},
+		{"y", "1:1: error: undefined: y"},
+		{`let y = "a";`, `y : String = "a"`},
+		{"x = y;", "1:1: error: Invalid type assignment"},
+		{"func twice(n Int) Int {\n\treturn n * 2;\n}", "twice : func(Int) Int"},
+		{"twice(x)", "10 : Int"},
+		{`PRINT(y + "b")`, "ab"},
+		{"let s String", `s : String = ""`},
+		{"let n Int = 4", "n : Int = 4"},
+		{"const Limit = 2 * 5", "Limit : Int = 10"},
+		{"Limit = 1;", "1:1: error: cannot assign to constant Limit"},
+		{"let z = 1 / 0;", "runtime error: division by zero"},
+		{"z", "1:1: error: undefined: z"},
+		{"func loop(n Int) Int {\n\treturn loop(n + 1);\n}", "loop : func(Int) Int"},
+		{"loop(0)", "runtime error: stack overflow"},
+		{":type twice", "1:1: error: cannot use function twice as a value"},
+		{":type let z = 1;", ":type expects an expression"},
+		{"let = 1;", `1:5: error: unexpected "="`},
+		{":oops", "unknown command :oops, try :help"},
+	}
+
+	var in bytes.Buffer
+	for _, test := range tests {
+		in.WriteString(test.input + "\n")
+	}
+
+	var out bytes.Buffer
+	repl.Start(&in, &out)
+
+	// Entries spanning several lines get a continuation prompt per line
+	results := strings.Split(out.String(), repl.PROMPT)[1:]
+	for i, test := range tests {
+		result := strings.TrimSpace(strings.Replace(results[i], repl.CONTINUATION, "", -1))
+		// Syntax errors go on with the expected tokens
+		if !strings.HasPrefix(result, test.out) {
+			t.Fatalf("test [%d] %q: wanted %q, got=%q", i, test.input, test.out, result)
+		}
+	}
+}
+
+func TestREPLAst(t *testing.T) {
+	var out bytes.Buffer
+	repl.NewSession(&out).Eval(":ast 1 + 2")
+
+	expected := `{"left":{"value":"1"},"right":{"value":"2"},"operator":"+"}`
+	result := out.String()
+	for _, rep := range []string{" ", "\n"} {
+		result = strings.Replace(result, rep, "", -1)
+	}
+
+	if result != expected {
+		t.Fatalf("wanted %s, got=%s", expected, result)
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aniketp/meego/src/checker"
+)
+
+func TestSwitchChecks(t *testing.T) {
+	tests := []ErrorTest{
+		{`let x = 1;
+		switch x {
+		case 1, 2:
+			x = 3;
+		case 3:
+		default:
+			x = 0;
+		}`, ""},
+		{`switch "a" {
+		case "a":
+		case "b":
+		}`, ""},
+		{`switch 1 {
+		case "one":
+		}`, "Case of type String in switch on Int"},
+		{`switch true {
+		case 1:
+		default:
+		}`, "Case of type Int in switch on Bool"},
+		{`switch 1 {
+		case 1, 2:
+		case 3, 1:
+		}`, "Duplicate case 1 in switch"},
+		{`switch 1 {
+		case -1:
+		case -1:
+		}`, "Duplicate case -1 in switch"},
+		{`switch "a" {
+		case "a", "a":
+		}`, `Duplicate case "a" in switch`},
+		{`switch 1 {
+		default:
+		case 1:
+		default:
+		}`, "Multiple defaults in switch"},
+		{`switch PRINT(1) {
+		}`, "Cannot switch on a value of type Nothing"},
+		{`switch y {
+		case 1:
+		}`, "undefined: y"},
+		// Cases are checked like blocks, each in a scope of its own
+		{`switch 1 {
+		case 1:
+			let x = 1;
+		case 2:
+			let x = "two";
+			x = 2;
+		}`, "Invalid type assignment"},
+		{`switch 1 {
+		case 1:
+			break;
+		}`, ""},
+		{`switch 1 {
+		case 1:
+			continue;
+		}`, "continue is not in a loop"},
+		{`for let i = 0; i < 3; i = i + 1 {
+			switch i {
+			case 1:
+				continue;
+			}
+		}`, ""},
+	}
+
+	runErrorTests(tests, t)
+}
+
+func TestSwitchExhaustive(t *testing.T) {
+	tests := []struct {
+		src     string
+		warning string // expected warning, empty if none
+	}{
+		{`switch true {
+		case true:
+		case false:
+		}`, ""},
+		{`switch true {
+		case true:
+		default:
+		}`, ""},
+		{`switch 1 < 2 {
+		case true:
+		}`, "Switch on Bool has no case for false and no default"},
+		{`switch true {
+		}`, "Switch on Bool has no case for true or false and no default"},
+		{`switch 1 {
+		case 1:
+		}`, ""},
+		{`let y = 3;
+		switch true {
+		case y > 5:
+		}`, "Switch on Bool has no default and its cases may not cover both true and false"},
+		{`let y = 3;
+		switch true {
+		case y > 5:
+		case true:
+		}`, "Switch on Bool has no default and its cases may not cover both true and false"},
+		// Constant cases covering both values are enough
+		{`let y = 3;
+		switch true {
+		case y > 5:
+		case true, false:
+		}`, ""},
+	}
+
+	for i, test := range tests {
+		diags, err := stringToDiagnostics(test.src)
+		if err != nil || diags.HasErrors() {
+			t.Fatalf("test %d: unexpected errors %v", i, err)
+		}
+
+		if test.warning == "" {
+			if len(diags) != 0 {
+				t.Fatalf("test %d: unexpected warning %s", i, diags)
+			}
+			continue
+		}
+
+		if len(diags) != 1 || diags[0].Severity != checker.WARNING ||
+			diags[0].Code != checker.NON_EXHAUSTIVE || diags[0].Message != test.warning {
+			t.Fatalf("test %d: expected warning %q, got=%v", i, test.warning, diags)
+		}
+	}
+}
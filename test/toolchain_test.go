@@ -0,0 +1,90 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/toolchain"
+)
+
+func TestToolchainEnvironment(t *testing.T) {
+	defer os.Setenv("CXX", os.Getenv("CXX"))
+	defer os.Setenv("CXXFLAGS", os.Getenv("CXXFLAGS"))
+
+	os.Setenv("CXX", "clang++")
+	os.Setenv("CXXFLAGS", "-Wall  -g")
+	tc := toolchain.Default()
+	tc.Opt = "2"
+
+	if tc.Family() != toolchain.CLANG {
+		t.Fatalf("wrong family of %s: %s", tc.CXX, tc.Family())
+	}
+
+	args := tc.Args("main", "main.cpp")
+	expected := []string{"-std=c++11", "-O2", "-fno-color-diagnostics",
+		"-Wall", "-g", "-o", "main", "main.cpp"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("wrong arguments: %v", args)
+	}
+
+	os.Setenv("CXX", "")
+	os.Setenv("CXXFLAGS", "")
+	tc = toolchain.Default()
+	tc.Std = "c++14"
+
+	args = tc.Args("main", "main.cpp")
+	expected = []string{"-std=c++14", "-fdiagnostics-color=never", "-o",
+		"main", "main.cpp"}
+	if tc.CXX != toolchain.DEFAULT_CXX || !reflect.DeepEqual(args, expected) {
+		t.Fatalf("wrong default toolchain: %s %v", tc.CXX, args)
+	}
+}
+
+func TestToolchainStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meego")
+	check(err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "main.cpp")
+	binary := filepath.Join(dir, "main")
+
+	// Warnings are kept apart, and don't fail the compilation
+	code := "int main() { int unused; return 0; }\n"
+	check(ioutil.WriteFile(source, []byte(code), 0644))
+
+	tc := toolchain.Default()
+	tc.Flags = []string{"-Wall"}
+	warnings, err := tc.Compile(binary, source)
+	if err != nil {
+		t.Fatalf("compilation failed: %s", err)
+	}
+
+	if !strings.Contains(warnings, "unused") {
+		t.Fatalf("expected a warning, got=%q", warnings)
+	}
+
+	// Errors are reported along with the exit status
+	check(ioutil.WriteFile(source, []byte("int main() { return x; }\n"), 0644))
+	_, err = tc.Compile(binary, source)
+	if e, ok := err.(*toolchain.Error); !ok || e.Status == 0 ||
+		!strings.Contains(e.Output, "error") {
+		t.Fatalf("expected a compilation error, got=%v", err)
+	}
+
+	// A failure without any output is still a failure
+	tc.CXX = "false"
+	_, err = tc.Compile(binary, source)
+	if e, ok := err.(*toolchain.Error); !ok || e.Status != 1 || e.Output != "" {
+		t.Fatalf("expected a silent failure, got=%v", err)
+	}
+
+	tc.CXX = "meego-no-such-compiler"
+	_, err = tc.Compile(binary, source)
+	if e, ok := err.(*toolchain.Error); !ok || e.Status != -1 {
+		t.Fatalf("expected a missing compiler, got=%v", err)
+	}
+}
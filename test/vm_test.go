@@ -0,0 +1,159 @@
+package test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aniketp/meego/src/compiler"
+	"github.com/aniketp/meego/src/interp"
+	"github.com/aniketp/meego/src/vm"
+)
+
+func TestVM(t *testing.T) {
+	for i, test := range outputTests {
+		output := RunBytecode(Bytecode(test.src))
+
+		// The VM must print exactly what the C++ does
+		expected := Compile(Generate(test.src))
+		if output != expected {
+			t.Fatalf("test [%d] failed wanted %q, got=%q", i, expected, output)
+		}
+	}
+}
+
+func TestBytecodeEncoding(t *testing.T) {
+	for i, test := range outputTests {
+		program := Bytecode(test.src)
+
+		var b bytes.Buffer
+		check(program.Encode(&b))
+		if !vm.IsBytecode(b.Bytes()) {
+			t.Fatalf("test [%d]: missing magic number", i)
+		}
+
+		decoded, err := vm.Decode(&b)
+		if err != nil {
+			t.Fatalf("test [%d]: %s", i, err)
+		}
+
+		output := RunBytecode(decoded)
+		if output != RunBytecode(program) {
+			t.Fatalf("test [%d] failed wanted %q, got=%q", i,
+				RunBytecode(program), output)
+		}
+	}
+}
+
+func TestBytecodeCorrupt(t *testing.T) {
+	var b bytes.Buffer
+	check(Bytecode(`let x = 1; PRINT(x);`).Encode(&b))
+	data := b.Bytes()
+
+	tests := []struct {
+		data []byte
+		err  string
+	}{
+		{[]byte("\x7fELF\x02\x01"), "not a meego bytecode file"},
+		{append([]byte(vm.Magic), 9), "unsupported bytecode version 9"},
+		{data[:len(data)-1], "unexpected EOF"},
+		// The last instruction of main is PRINT, make it an unknown opcode
+		{append(data[:len(data)-1:len(data)-1], 0xff), "unknown opcode 255"},
+	}
+
+	for i, test := range tests {
+		_, err := vm.Decode(bytes.NewReader(test.data))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("test [%d]: expected error %q, got=%v", i, test.err, err)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	program := Bytecode(`
+		func inc(x Int) Int {
+			return x + 1;
+		}
+		PRINT(inc(41));`)
+
+	var b bytes.Buffer
+	program.Disassemble(&b)
+	expected := `== inc (params 1, locals 1) ==
+0000 GET_LOCAL 0
+0003 CONSTANT 0 (Int "1")
+0006 METHOD 0 (PLUS)
+0009 RETURN
+0010 CONSTANT 1 (Nothing "")
+0013 RETURN
+
+== main (params 0, locals 0) ==
+0000 CONSTANT 2 (Int "41")
+0003 CALL 0 (inc)
+0006 PRINT
+0007 CONSTANT 1 (Nothing "")
+0010 POP
+
+`
+	if b.String() != expected {
+		t.Fatalf("wanted:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+// Compare the execution backends on a recursive program
+const benchSource = `
+	func fib(n Int) Int {
+		let r = n;
+		if (1 < n) {
+			r = fib(n - 1) + fib(n - 2);
+		} else {
+		}
+		return r;
+	}
+	PRINT(fib(20));`
+
+func BenchmarkVM(b *testing.B) {
+	program := Bytecode(benchSource)
+	for n := 0; n < b.N; n++ {
+		check(vm.Run(program, ioutil.Discard))
+	}
+}
+
+func BenchmarkInterp(b *testing.B) {
+	res, err := compiler.Compile([]byte(benchSource), &compiler.Options{CheckOnly: true})
+	check(err)
+	for n := 0; n < b.N; n++ {
+		check(interp.Run(res.Program, res.Info, ioutil.Discard))
+	}
+}
+
+// Includes starting the process, like running a script would
+func BenchmarkCPP(b *testing.B) {
+	Compile(Generate(benchSource))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		check(exec.Command("./apple").Run())
+	}
+}
+
+// Includes compiling the source, like `meego run -vm` would
+func BenchmarkVMStartup(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		check(vm.Run(Bytecode(benchSource), ioutil.Discard))
+	}
+}
+
+func TestVMStackOverflow(t *testing.T) {
+	program := Bytecode(`
+		func f(n Int) Int {
+			return f(n + 1);
+		}
+		PRINT(f(0));`)
+
+	var out bytes.Buffer
+	err := vm.Run(program, &out)
+	if _, ok := err.(interp.RuntimeError); !ok || err.Error() != "runtime error: stack overflow in f" {
+		t.Fatalf("got %#v", err)
+	}
+}